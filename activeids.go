@@ -0,0 +1,118 @@
+package gobark
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// clearBody is the body of the request sent by ClearNotification,
+// which the device does not display.
+const clearBody = "clear"
+
+// idTracker remembers the IDs of the notifications sent recently.
+type idTracker struct {
+	mu     sync.Mutex
+	ttl    time.Duration
+	sentAt map[string]time.Time
+}
+
+func newIDTracker(ttl time.Duration) *idTracker {
+	return &idTracker{
+		ttl:    ttl,
+		sentAt: make(map[string]time.Time),
+	}
+}
+
+// WithActiveIDTracking makes the client remember the ID of every notification
+// sent with an ID for ttl, so that the notifications still shown on the device
+// can be listed with ActiveIDs and removed with ClearNotification.
+// IDs are forgotten once ttl has passed since the notification was last sent.
+func WithActiveIDTracking(ttl time.Duration) ClientOption {
+	return func(c *Client) error {
+		if ttl <= 0 {
+			return fmt.Errorf("active id ttl must be positive")
+		}
+		c.activeIDs = newIDTracker(ttl)
+		return nil
+	}
+}
+
+// ActiveIDs returns the IDs of the notifications sent within the tracking TTL
+// and not cleared, in sorted order. It returns nil unless WithActiveIDTracking is set.
+func (c *Client) ActiveIDs() []string {
+	if c.activeIDs == nil {
+		return nil
+	}
+	return c.activeIDs.active(c.now())
+}
+
+// ClearNotification removes the notification with the given ID from the device,
+// by sending the id with the delete parameter, and stops tracking it.
+// It requires a Bark app version that supports deleting notifications.
+func (c *Client) ClearNotification(ctx context.Context, id string) error {
+	if id == "" {
+		return fmt.Errorf("notification id is required")
+	}
+
+	n := NewNotification(clearBody, WithID(id))
+	n.delete = true
+	if _, err := c.send(ctx, n); err != nil {
+		return err
+	}
+
+	if c.activeIDs != nil {
+		c.activeIDs.forget(id)
+	}
+	return nil
+}
+
+// record remembers id as sent at now.
+func (t *idTracker) record(id string, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.evictExpired(now)
+	t.sentAt[id] = now
+}
+
+// forget stops tracking id.
+func (t *idTracker) forget(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.sentAt, id)
+}
+
+// reset stops tracking every id.
+func (t *idTracker) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sentAt = make(map[string]time.Time)
+}
+
+// active returns the tracked ids sorted, after dropping those expired at now.
+func (t *idTracker) active(now time.Time) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.evictExpired(now)
+	ids := make([]string, 0, len(t.sentAt))
+	for id := range t.sentAt {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// evictExpired removes the ids sent more than the TTL before now.
+func (t *idTracker) evictExpired(now time.Time) {
+	for id, sentAt := range t.sentAt {
+		if now.Sub(sentAt) >= t.ttl {
+			delete(t.sentAt, id)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package gobark
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWithActiveIDTracking(t *testing.T) {
+	client, transport := newRecordingClient(t, WithActiveIDTracking(time.Hour))
+
+	ctx := context.Background()
+	for _, id := range []string{"deploy-api", "deploy-web", "backup"} {
+		if err := client.Send(ctx, "in progress", WithID(id)); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+	if err := client.Send(ctx, "no id"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if err := client.ClearNotification(ctx, "deploy-web"); err != nil {
+		t.Fatalf("ClearNotification() error = %v", err)
+	}
+
+	want := []string{"backup", "deploy-api"}
+	if got := client.ActiveIDs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ActiveIDs() = %v, want %v", got, want)
+	}
+
+	requests := transport.Requests()
+	clear := requests[len(requests)-1]
+	if clear.Params["id"] != "deploy-web" || clear.Params["delete"] != "1" {
+		t.Errorf("clear request params = %v, want id=deploy-web and delete=1", clear.Params)
+	}
+}
+
+func TestIDTracker_Expiry(t *testing.T) {
+	tracker := newIDTracker(time.Minute)
+	now := time.Now()
+
+	tracker.record("old", now)
+	tracker.record("recent", now.Add(30*time.Second))
+
+	if got := tracker.active(now.Add(time.Minute)); !reflect.DeepEqual(got, []string{"recent"}) {
+		t.Errorf("active() = %v, want [recent]", got)
+	}
+}
@@ -3,17 +3,119 @@
 package gobark
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math/rand"
+	"net"
 	"net/http"
 	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/time/rate"
 )
 
+// ErrKeyRequired is returned when a client has no Bark key to send with.
+var ErrKeyRequired = errors.New("bark key is required")
+
 // Client represents a Bark API client.
 type Client struct {
-	baseURL string
-	key     string
-	client  *http.Client
+	baseURL       string
+	key           string
+	keyProvider   KeyProvider
+	client        *http.Client
+	failoverURLs  []string
+	balancedURLs  []string
+	balanceNext   atomic.Uint64
+	maxBodyBytes  int
+	maxBodyLines  int
+	postMode      bool
+	formEncoded   bool
+	gzipThreshold int
+	deviceField   DeviceField
+	groupLimiters map[string]*rate.Limiter
+	dedup         *dedupCache
+	observer      Observer
+
+	hedgeDelay          time.Duration
+	maxRetries          int
+	retryBackoff        time.Duration
+	retryableStatuses   map[int]bool
+	backoffJitter       float64
+	randFloat           func() float64
+	now                 func() time.Time
+	defaultSubtitle     string
+	defaultGroup        string
+	defaultBody         string
+	bodyTransforms      []func(string) string
+	titleTransforms     []func(string) string
+	subtitleTransforms  []func(string) string
+	normalizeUnicode    bool
+	unicodeForm         norm.Form
+	slowSendThreshold   time.Duration
+	slowSendCallback    func(duration time.Duration)
+	errorResponseLogger func(status int, body []byte)
+	batchSize           int
+	normalizeSound      bool
+	deadLetter          DeadLetterFunc
+	encode              func(string) string
+	base64Body          bool
+	connectTimeout      time.Duration
+	resolver            *net.Resolver
+	sendSlots           chan struct{}
+
+	responseHeaderCallback func(http.Header)
+	sendInterceptor        func(ctx context.Context, req *http.Request) error
+	attemptTimeout         time.Duration
+	retryDeadline          time.Duration
+	maxRetryAfter          time.Duration
+	followRedirects        *bool
+	redactLogging          bool
+	keyPattern             *regexp.Regexp
+	soundCache             soundCache
+	activeIDs              *idTracker
+	levelVolumes           map[NotificationLevel]int
+	archiveGroups          map[string]bool
+	categorySounds         map[string]string
+	history                *sendHistory
+	minLevel               NotificationLevel
+	defaultLevel           NotificationLevel
+	defaultSound           string
+	retryableErrors        map[ErrorClass]bool
+	retryableBodyCodes     map[int]bool
+	successBodyCodes       map[int]bool
+	cancelAsSuccess        bool
+	maxURLLength           int
+	hostHeader             string
+	decodeResponse         func(body []byte) (*Response, error)
+	broadcastConcurrency   int
+	streamBuffer           *int
+	keyHeader              string
+	tlsMinVersion          uint16
+	probe                  *probeState
+	strictSounds           bool
+	httpTrace              func(info TraceInfo)
+	queue                  *sendQueue
+	requestIDGenerator     func() string
+	requestIDAsID          bool
+	metrics                SendMetricsObserver
+	idempotencyStore       IdempotencyStore
+	serverTimeLayout       string
+	bodyTimestampLayout    string
+	serverClockOffset      atomic.Int64
 }
 
 // NotificationLevel represents the level of notification importance.
@@ -30,114 +132,455 @@ const (
 	LevelCritical NotificationLevel = "critical"
 
 	defaultTitle = "无名消息"
+
+	// defaultCriticalSound is played for critical alerts without an explicit sound.
+	defaultCriticalSound = "alarm"
+
+	// maxErrorResponseBytes limits how much of an error response body is passed
+	// to the logger set by WithErrorResponseLogger.
+	maxErrorResponseBytes = 4 << 10
+
+	// maxResponseBytes is the maximum number of bytes of a response body read.
+	maxResponseBytes = 1 << 20
+
+	// bodyEncodingBase64 is the encoding parameter value sent with base64-encoded bodies.
+	bodyEncodingBase64 = "base64"
+
+	// truncationMarker is appended to bodies shortened by WithMaxBodyBytes or WithMaxBodyLines.
+	truncationMarker = "…"
 )
 
-// notification represents a Bark notification request.
-type notification struct {
+// Notification represents a Bark notification request.
+// Use NewNotification to create one; its fields are set through Options.
+type Notification struct {
 	title      string
 	body       string
 	subtitle   string
 	icon       string
+	image      string
 	sound      string
 	level      NotificationLevel
 	isCritical bool
+	url        string
+	copyURL    bool
+	group      string
+	// category is the category set by WithCategory, used to pick the sound.
+	category   string
+	id         string
+	collapseID string
+	expiration time.Duration
+	// volume is the critical alert volume set by WithVolume, or nil if not set.
+	volume *int
+	haptic string
+	// archive is whether the server archives the notification, set by
+	// WithArchiveGroups, or nil to use the server default.
+	archive *bool
+	// soundRepeat is the number of times set by WithSoundRepeat to play the sound.
+	soundRepeat int
+	// soundPreferences lists the sounds set by WithSoundPreferring, most preferred first.
+	soundPreferences []string
+	bodyFields       map[string]string
+	titleDelimiter   string
+	autoID           bool
+	contentAvailable bool
+	delete           bool
+
+	bodyEncoding string
+	// dedupHash identifies the notification for WithDedupWindow, set by prepare.
+	dedupHash string
 }
 
 // Option represents a function that modifies the notification request.
-type Option func(*notification)
+type Option func(*Notification)
 
 // NewClient creates a new Bark client with the specified base URL and key.
-func NewClient(baseURL, key string) (*Client, error) {
+// Additional options can be provided to customize the client.
+func NewClient(baseURL, key string, opts ...ClientOption) (*Client, error) {
+	if key == "" {
+		return nil, ErrKeyRequired
+	}
+
+	return newClient(baseURL, key, nil, opts)
+}
+
+// newClient creates a client that uses either the static key or, if set, the key provider.
+func newClient(baseURL, key string, keyProvider KeyProvider, opts []ClientOption) (*Client, error) {
 	if baseURL == "" {
 		baseURL = "https://api.day.app"
 	}
 
-	if key == "" {
-		return nil, fmt.Errorf("bark key is required")
+	c := &Client{
+		baseURL:     baseURL,
+		key:         key,
+		keyProvider: keyProvider,
+		client:      &http.Client{},
+		observer:    NopObserver{},
+		deviceField: DeviceKeyField,
+		randFloat:   rand.Float64,
+		now:         time.Now,
+		encode:      url.PathEscape,
+
+		decodeResponse: decodeJSONResponse,
+	}
+
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.keyProvider == nil {
+		if err := c.validateKey(c.key); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.configureTransport(); err != nil {
+		return nil, err
 	}
+	c.configureRedirects()
 
-	return &Client{
-		baseURL: baseURL,
-		key:     key,
-		client:  &http.Client{},
-	}, nil
+	return c, nil
 }
 
 // WithTitle sets the notification title.
 func WithTitle(title string) Option {
-	return func(n *notification) {
+	return func(n *Notification) {
 		n.title = title
 	}
 }
 
 // WithSubtitle sets the notification subtitle.
 func WithSubtitle(subtitle string) Option {
-	return func(n *notification) {
+	return func(n *Notification) {
 		n.subtitle = subtitle
 	}
 }
 
+// WithSplitTitle splits the title at the first occurrence of delim into the title
+// and the subtitle, trimming the spaces around both, so that WithTitle("Build | main branch")
+// with WithSplitTitle("|") sends the title "Build" and the subtitle "main branch".
+// The subtitle replaces any set with WithSubtitle. Titles without delim are unchanged.
+// It applies once all options are set, whatever their order.
+func WithSplitTitle(delim string) Option {
+	return func(n *Notification) {
+		n.titleDelimiter = delim
+	}
+}
+
 // WithIcon sets the notification icon URL (iOS 15+ only).
 func WithIcon(iconURL string) Option {
-	return func(n *notification) {
+	return func(n *Notification) {
 		n.icon = iconURL
 	}
 }
 
 // WithSound sets the notification sound.
 func WithSound(sound string) Option {
-	return func(n *notification) {
+	return func(n *Notification) {
 		n.sound = sound
+		n.soundPreferences = nil
+	}
+}
+
+// WithSoundRepeat plays the notification sound count times, for alerts that must
+// not be missed. It sends the repeat parameter, which requires a server that
+// supports it, together with call=1, so that the stock Bark server falls back to
+// looping the sound for 30 seconds. A count of 0 plays the sound once; sending
+// fails if count is negative.
+func WithSoundRepeat(count int) Option {
+	return func(n *Notification) {
+		n.soundRepeat = count
+	}
+}
+
+// WithHaptic sets the haptic pattern played on delivery, independently of the sound.
+// Combined with no sound, this makes a vibrate-only notification.
+// The stock Bark server does not forward this parameter: it requires a server
+// and client app that support the haptic parameter.
+func WithHaptic(pattern string) Option {
+	return func(n *Notification) {
+		n.haptic = pattern
+	}
+}
+
+// WithImageData attaches an image to the notification, sent inline as a base64
+// data URL (data:<mime>;base64,...) in the image parameter. Since such URLs are
+// too long for a GET request, a notification with image data is always sent in POST mode.
+// The stock Bark server only accepts image URLs it can download: this requires a
+// server or fork that accepts inline data URLs.
+func WithImageData(data []byte, mime string) Option {
+	return func(n *Notification) {
+		n.image = "data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(data)
 	}
 }
 
 // WithTimeSensitive sets the notification as time-sensitive.
 func WithTimeSensitive() Option {
-	return func(n *notification) {
+	return func(n *Notification) {
 		n.level = LevelTimeSensitive
 	}
 }
 
+// WithTimeSensitiveTTL sets the notification as time-sensitive and expiring after d,
+// sent as the expiration parameter, since a time-sensitive notification is of little
+// use when it arrives late. Servers that forward it as the apns-expiration header
+// let APNs stop trying to deliver the notification once d has elapsed.
+// The expiration is sent in whole seconds, rounded up, so that durations under
+// a second are not sent as 0, which APNs takes as "deliver once, do not store".
+// The stock Bark server does not forward this parameter: it requires a server
+// that supports the expiration parameter.
+func WithTimeSensitiveTTL(d time.Duration) Option {
+	return func(n *Notification) {
+		n.level = LevelTimeSensitive
+		n.expiration = d
+	}
+}
+
+// WithPassive sets the notification as passive: it is added to the notification
+// list without lighting up the screen. Passive notifications play no sound
+// unless set with WithSound.
+func WithPassive() Option {
+	return func(n *Notification) {
+		n.level = LevelPassive
+		n.isCritical = false
+	}
+}
+
+// maxVolume is the loudest critical alert volume.
+const maxVolume = 10
+
+// WithVolume sets the volume of a critical alert, from 0 (silent) to 10 (loudest).
+// Sending fails if volume is outside this range. The Bark app only applies the
+// volume to critical alerts, see WithCriticalNotify.
+func WithVolume(volume int) Option {
+	return func(n *Notification) {
+		n.volume = &volume
+	}
+}
+
+// WithContentAvailable sends the notification as a silent background push that
+// wakes the app without showing anything, by setting the contentAvailable parameter
+// that servers supporting it map to APNs content-available. The title and body
+// may then be ignored by the device. It requires a server that forwards the parameter;
+// combine it with WithPassive so that servers without support deliver it quietly.
+func WithContentAvailable() Option {
+	return func(n *Notification) {
+		n.contentAvailable = true
+	}
+}
+
 // WithCriticalNotify sets the notification as a critical alert.
+// The sound defaults to "alarm" unless set with WithSound.
 func WithCriticalNotify() Option {
-	return func(n *notification) {
+	return func(n *Notification) {
 		n.level = LevelCritical
 		n.isCritical = true
 	}
 }
 
-// buildNotificationURL constructs the complete notification URL with all parameters
-func (c *Client) buildNotificationURL(n *notification) string {
-	// URL encode the body to handle special characters, especially newlines (\n)
-	encodedBody := url.PathEscape(n.body)
+// WithFields sets labeled values to show in the notification, such as a host name
+// and its CPU usage. When no body is given, the body is made of one "key: value"
+// line per field, sorted by key.
+func WithFields(fields map[string]string) Option {
+	return func(n *Notification) {
+		n.bodyFields = fields
+	}
+}
 
-	// Build the URL path based on available parameters
-	urlPath := c.key
-	if n.title != "" && n.subtitle != "" {
-		urlPath = fmt.Sprintf("%s/%s/%s/%s", urlPath, url.PathEscape(n.title), url.PathEscape(n.subtitle), encodedBody)
-	} else if n.title != "" {
-		urlPath = fmt.Sprintf("%s/%s/%s", urlPath, url.PathEscape(n.title), encodedBody)
-	} else {
-		urlPath = fmt.Sprintf("%s/%s", urlPath, encodedBody)
+// WithURL sets the URL to open when the notification is tapped.
+func WithURL(link string) Option {
+	return func(n *Notification) {
+		n.url = link
+	}
+}
+
+// WithCopyURL sets the copy content to the URL given by WithURL,
+// so that the opened link is also copied to the clipboard.
+// Sending fails if WithURL is not provided.
+func WithCopyURL() Option {
+	return func(n *Notification) {
+		n.copyURL = true
+	}
+}
+
+// WithGroup sets the group the notification is listed under on the device.
+func WithGroup(group string) Option {
+	return func(n *Notification) {
+		n.group = group
+	}
+}
+
+// WithID sets the notification ID.
+// Sending a notification with the same ID again updates the existing notification
+// on the device instead of adding a new one.
+func WithID(id string) Option {
+	return func(n *Notification) {
+		n.id = id
+	}
+}
+
+// WithCollapseID sets the APNs collapse ID, sent as the collapseId parameter for
+// servers that forward it as the apns-collapse-id header. Unlike WithID, which the
+// Bark app uses to update a notification in place and in its history, the collapse ID
+// is handled by APNs: notifications sharing it replace each other in the
+// notification center, and only the latest is delivered to a device that was offline.
+// It requires a server that forwards the parameter.
+func WithCollapseID(id string) Option {
+	return func(n *Notification) {
+		n.collapseID = id
 	}
+}
+
+// WithAutoID sets the notification ID to the notification's fingerprint, unless
+// an ID is set with WithID. Resending a notification with identical content then
+// updates the existing notification on the device instead of adding a duplicate.
+func WithAutoID() Option {
+	return func(n *Notification) {
+		n.autoID = true
+	}
+}
 
-	// Build the query parameters for additional options
+// effectiveLevel returns the level the notification is sent with.
+func (n *Notification) effectiveLevel() NotificationLevel {
+	if n.isCritical {
+		return LevelCritical
+	}
+	return n.level
+}
+
+// defaultSound returns the sound for a notification without an explicit sound,
+// given the client default sound set by WithDefaultSound, if any.
+func defaultSound(n *Notification, clientDefault string) string {
+	switch {
+	case n.level == LevelPassive:
+		// Passive notifications are meant to be silent
+		return ""
+	case clientDefault != "":
+		return clientDefault
+	case n.isCritical:
+		return defaultCriticalSound
+	default:
+		return ""
+	}
+}
+
+// Validate checks the notification against the rules Send applies before sending it:
+// the body must not be empty, WithCopyURL requires WithURL, the volume must be
+// between 0 and 10, the sound repeat count must not be negative and the level
+// must be one of the NotificationLevel constants.
+// Send applies the client defaults first, so a notification with an empty body
+// is accepted by a client with a default body.
+func (n *Notification) Validate() error {
+	if n.body == "" {
+		return fmt.Errorf("notification body is required")
+	}
+	if n.copyURL && n.url == "" {
+		return fmt.Errorf("copy url requires a url")
+	}
+	if n.volume != nil && (*n.volume < 0 || *n.volume > maxVolume) {
+		return fmt.Errorf("volume must be between 0 and %d, got %d", maxVolume, *n.volume)
+	}
+	if n.soundRepeat < 0 {
+		return fmt.Errorf("sound repeat count must not be negative, got %d", n.soundRepeat)
+	}
+	switch n.level {
+	case "", LevelActive, LevelTimeSensitive, LevelPassive, LevelCritical:
+	default:
+		return fmt.Errorf("unsupported notification level %q", n.level)
+	}
+	return nil
+}
+
+// params returns the notification parameters that are not part of the URL path.
+func (n *Notification) params() url.Values {
 	query := url.Values{}
 	if n.icon != "" {
 		query.Set("icon", n.icon)
 	}
+	if n.image != "" {
+		query.Set("image", n.image)
+	}
 	if n.sound != "" {
 		query.Set("sound", n.sound)
 	}
+	if n.haptic != "" {
+		query.Set("haptic", n.haptic)
+	}
+	if n.soundRepeat > 0 {
+		query.Set("call", "1")
+		query.Set("repeat", strconv.Itoa(n.soundRepeat))
+	}
 	if n.level != "" {
 		query.Set("level", string(n.level))
 	}
 	if n.isCritical {
 		query.Set("level", "critical")
 	}
+	if n.volume != nil {
+		query.Set("volume", strconv.Itoa(*n.volume))
+	}
+	if n.url != "" {
+		query.Set("url", n.url)
+	}
+	if n.copyURL {
+		query.Set("copy", n.url)
+	}
+	if n.group != "" {
+		query.Set("group", n.group)
+	}
+	if n.archive != nil {
+		isArchive := "0"
+		if *n.archive {
+			isArchive = "1"
+		}
+		query.Set("isArchive", isArchive)
+	}
+	if n.id != "" {
+		query.Set("id", n.id)
+	}
+	if n.collapseID != "" {
+		query.Set("collapseId", n.collapseID)
+	}
+	if n.expiration > 0 {
+		query.Set("expiration", strconv.FormatInt(int64((n.expiration+time.Second-1)/time.Second), 10))
+	}
+	if n.contentAvailable {
+		query.Set("contentAvailable", "1")
+	}
+	if n.delete {
+		query.Set("delete", "1")
+	}
+	if n.bodyEncoding != "" {
+		query.Set("encoding", n.bodyEncoding)
+	}
+
+	return query
+}
+
+// buildNotificationURL constructs the complete notification URL with all parameters
+func (c *Client) buildNotificationURL(baseURL, key string, n *Notification) string {
+	// URL encode the body to handle special characters, especially newlines (\n)
+	encodedBody := c.encode(n.body)
+
+	// Build the URL path based on available parameters
+	urlPath := key
+	if n.title != "" && n.subtitle != "" {
+		urlPath = fmt.Sprintf("%s/%s/%s/%s", urlPath, c.encode(n.title), c.encode(n.subtitle), encodedBody)
+	} else if n.title != "" {
+		urlPath = fmt.Sprintf("%s/%s/%s", urlPath, c.encode(n.title), encodedBody)
+	} else {
+		urlPath = fmt.Sprintf("%s/%s", urlPath, encodedBody)
+	}
+	if key == "" {
+		// The key is sent in a header, so the path starts with the title or body
+		urlPath = strings.TrimPrefix(urlPath, "/")
+	}
+
+	query := n.params()
 
 	// Construct the final URL
-	apiURL := fmt.Sprintf("%s/%s", c.baseURL, urlPath)
+	apiURL := joinURL(baseURL, urlPath)
 	if len(query) > 0 {
 		apiURL += "?" + query.Encode()
 	}
@@ -145,40 +588,615 @@ func (c *Client) buildNotificationURL(n *notification) string {
 	return apiURL
 }
 
+// joinURL appends path to baseURL, which may include a path of its own
+// (e.g. https://host/notify) with or without a trailing slash.
+func joinURL(baseURL, path string) string {
+	return strings.TrimRight(baseURL, "/") + "/" + path
+}
+
 // Send sends a push notification through Bark.
-// The body parameter is required and represents the main content of the notification.
+// The body parameter represents the main content of the notification.
+// It is required unless a default body is set with WithDefaultBody.
 // Additional options can be provided to customize the notification.
 func (c *Client) Send(ctx context.Context, body string, opts ...Option) error {
-	if body == "" {
-		return fmt.Errorf("notification body is required")
+	_, err := c.SendStatus(ctx, body, opts...)
+	return err
+}
+
+// SendStatus sends a push notification like Send and also returns the HTTP status
+// code of the server's response. The status code is 0 if no response was received.
+func (c *Client) SendStatus(ctx context.Context, body string, opts ...Option) (int, error) {
+	n, err := c.newNotification(body, opts)
+	if err != nil {
+		return 0, err
 	}
 
-	n := &notification{
-		title: defaultTitle,
-		body:  body,
+	return c.deliverWithDeadLetter(ctx, n, body, opts)
+}
+
+// deliverWithDeadLetter delivers the prepared notification n, created from body
+// and opts, and passes it to the dead letter handler if it could not be delivered,
+// unless the send was cancelled under WithTreatCancelAsSuccess.
+func (c *Client) deliverWithDeadLetter(ctx context.Context, n *Notification, body string, opts []Option) (int, error) {
+	statusCode, err := c.deliver(ctx, n)
+	if c.cancelAsSuccess && errors.Is(err, context.Canceled) {
+		return statusCode, nil
+	}
+	if err != nil && !errors.Is(err, ErrDuplicateSuppressed) && !errors.Is(err, ErrBelowMinLevel) && c.deadLetter != nil {
+		c.deadLetter(ctx, body, opts, err)
 	}
 
-	for _, opt := range opts {
-		opt(n)
+	return statusCode, err
+}
+
+// SendVerbose sends a push notification like Send and also returns the exact URL
+// of the last request sent, after encoding, transforms and any send interceptor,
+// which helps debug encoding issues. The URL is empty if no request was sent.
+func (c *Client) SendVerbose(ctx context.Context, body string, opts ...Option) (finalURL string, err error) {
+	var mu sync.Mutex
+	ctx = withRequestSink(ctx, func(req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		finalURL = req.URL.String()
+	})
+
+	_, err = c.SendStatus(ctx, body, opts...)
+
+	mu.Lock()
+	defer mu.Unlock()
+	return finalURL, err
+}
+
+// BuildURL returns the URL of the request that Send would send for body and opts
+// to the primary server, without sending it. In POST mode this is the /push endpoint.
+// Sound preferences set with WithSoundPreferring are not resolved.
+func (c *Client) BuildURL(ctx context.Context, body string, opts ...Option) (string, error) {
+	n, err := c.newNotification(body, opts)
+	if err != nil {
+		return "", err
 	}
 
-	apiURL := c.buildNotificationURL(n)
+	key, err := c.resolveKey(ctx)
+	if err != nil {
+		return "", err
+	}
 
-	// Create and send the request
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	req, err := c.newRequest(ctx, c.baseURL, key, n)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return "", err
+	}
+	return req.URL.String(), nil
+}
+
+// deliver sends the prepared notification n unless it is below the minimum level
+// or a duplicate of one sent within the dedup window.
+func (c *Client) deliver(ctx context.Context, n *Notification) (int, error) {
+	if c.belowMinLevel(n) {
+		return 0, ErrBelowMinLevel
 	}
 
-	resp, err := c.client.Do(req)
+	// Drop notifications identical to one sent within the dedup window
+	if c.dedup != nil && c.dedup.seen(n.dedupHash, c.now()) {
+		return 0, ErrDuplicateSuppressed
+	}
+
+	statusCode, err := c.send(ctx, n)
+	if err != nil && c.dedup != nil {
+		c.dedup.forget(n.dedupHash)
+	}
+	if err == nil && c.activeIDs != nil && n.id != "" {
+		c.activeIDs.record(n.id, c.now())
+	}
+
+	return statusCode, err
+}
+
+// newNotification builds the notification for body and opts, applying the client defaults.
+func (c *Client) newNotification(body string, opts []Option) (*Notification, error) {
+	return c.prepare(NewNotification(body, opts...))
+}
+
+// prepare returns a copy of n with the client defaults and transforms applied,
+// ready to be sent. n itself is left unchanged.
+func (c *Client) prepare(orig *Notification) (*Notification, error) {
+	copied := *orig
+	n := &copied
+	if n.body == "" {
+		n.body = c.defaultBody
+	}
+
+	if n.subtitle == "" {
+		n.subtitle = c.defaultSubtitle
+	}
+	if n.group == "" {
+		n.group = c.defaultGroup
+	}
+	if n.level == "" && c.defaultLevel != "" {
+		n.level = c.defaultLevel
+		n.isCritical = n.level == LevelCritical
+	}
+	if sound, ok := c.categorySounds[n.category]; ok && n.sound == "" && n.category != "" {
+		n.sound = sound
+	}
+	if n.sound == "" {
+		n.sound = defaultSound(n, c.defaultSound)
+	}
+	if c.normalizeSound && n.sound != "" {
+		n.sound = canonicalSound(n.sound)
+	}
+	if volume, ok := c.levelVolumes[n.effectiveLevel()]; ok && n.volume == nil {
+		n.volume = &volume
+	}
+	if archive, ok := c.archiveGroups[n.group]; ok {
+		n.archive = &archive
+	}
+
+	if err := n.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Identify duplicates by the content before time stamps, which differ
+	// between otherwise identical notifications
+	if c.dedup != nil {
+		n.dedupHash = dedupHash(n.title, n.body)
+	}
+	if c.bodyTimestampLayout != "" {
+		n.body = c.now().Format(c.bodyTimestampLayout) + " " + n.body
+	}
+	if c.serverTimeLayout != "" {
+		n.body = c.serverNow().Format(c.serverTimeLayout) + " " + n.body
+	}
+
+	for _, transform := range c.bodyTransforms {
+		n.body = transform(n.body)
+	}
+	if n.title != "" {
+		for _, transform := range c.titleTransforms {
+			n.title = transform(n.title)
+		}
+	}
+	if n.subtitle != "" {
+		for _, transform := range c.subtitleTransforms {
+			n.subtitle = transform(n.subtitle)
+		}
+	}
+
+	if c.normalizeUnicode {
+		n.title = c.unicodeForm.String(n.title)
+		n.subtitle = c.unicodeForm.String(n.subtitle)
+		n.body = c.unicodeForm.String(n.body)
+	}
+
+	if c.maxBodyLines > 0 {
+		n.body = truncateLines(n.body, c.maxBodyLines)
+	}
+	if c.maxBodyBytes > 0 {
+		n.body = truncateBody(n.body, c.maxBodyBytes)
+	}
+
+	if c.base64Body {
+		n.body = base64.StdEncoding.EncodeToString([]byte(n.body))
+		n.bodyEncoding = bodyEncodingBase64
+	}
+
+	return n, nil
+}
+
+// send delivers the prepared notification n and returns the response status code.
+func (c *Client) send(ctx context.Context, n *Notification) (int, error) {
+	if err := c.runProbe(ctx); err != nil {
+		return 0, err
+	}
+
+	// Wait for the group's rate limiter, if any, before sending
+	if limiter, ok := c.groupLimiters[n.group]; ok {
+		if err := limiter.Wait(ctx); err != nil {
+			return 0, fmt.Errorf("group %q rate limited: %w", n.group, err)
+		}
+	}
+
+	release, err := c.acquireSendSlot(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	key, err := c.resolveKey(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx = c.withRequestID(ctx, n)
+	c.resolveSound(ctx, n)
+	if err := c.checkSound(ctx, n); err != nil {
+		return 0, err
+	}
+
+	if c.slowSendCallback != nil {
+		start := time.Now()
+		defer func() {
+			if elapsed := time.Since(start); elapsed > c.slowSendThreshold {
+				c.slowSendCallback(elapsed)
+			}
+		}()
+	}
+
+	return c.retry(ctx, func(ctx context.Context) (int, error) {
+		return c.sendOnce(ctx, n, func(ctx context.Context, baseURL string) (*http.Request, error) {
+			return c.newRequest(ctx, baseURL, key, n)
+		})
+	})
+}
+
+// requestBuilder creates the request that delivers a notification through the server at baseURL.
+type requestBuilder func(ctx context.Context, baseURL string) (*http.Request, error)
+
+// sendOnce makes a single attempt to deliver n with the requests created by build,
+// trying the primary server first and then each failover server in order.
+// It returns the status code and error of the last server tried.
+func (c *Client) sendOnce(ctx context.Context, n *Notification, build requestBuilder) (int, error) {
+	var (
+		lastStatus int
+		lastErr    error
+	)
+	for _, baseURL := range c.baseURLs() {
+		c.observer.BeforeBuild(ctx)
+		req, err := build(ctx, baseURL)
+		if err != nil {
+			return 0, err
+		}
+		if id, ok := RequestIDFromContext(ctx); ok {
+			req.Header.Set(RequestIDHeader, id)
+		}
+		if c.hostHeader != "" {
+			req.Host = c.hostHeader
+		}
+		c.observer.AfterBuild(ctx, c.observedURL(req))
+
+		if c.sendInterceptor != nil {
+			if err := c.sendInterceptor(ctx, req); err != nil {
+				return 0, &permanentError{err: fmt.Errorf("send aborted by interceptor: %w", err)}
+			}
+		}
+
+		var statusCode int
+		if c.hedgeDelay > 0 && n.id != "" {
+			statusCode, err = c.doHedged(req)
+		} else {
+			statusCode, err = c.do(req)
+		}
+		if err == nil {
+			return statusCode, nil
+		}
+		lastStatus, lastErr = statusCode, err
+
+		if !shouldFailover(ctx, statusCode) {
+			break
+		}
+	}
+
+	return lastStatus, lastErr
+}
+
+// truncateBody shortens body to at most maxBytes bytes, including the truncation marker.
+// The body is cut on a rune boundary so that multi-byte characters are never split.
+// If maxBytes is too small for the first rune, that rune is kept anyway, so that
+// the body is never emptied.
+func truncateBody(body string, maxBytes int) string {
+	if len(body) <= maxBytes {
+		return body
+	}
+
+	marker := truncationMarker
+	limit := maxBytes - len(marker)
+	if limit <= 0 {
+		marker = ""
+		limit = maxBytes
+	}
+
+	for limit > 0 && !utf8.RuneStart(body[limit]) {
+		limit--
+	}
+	if limit == 0 {
+		_, size := utf8.DecodeRuneInString(body)
+		return body[:size]
+	}
+
+	return body[:limit] + marker
+}
+
+// truncateLines keeps the first maxLines lines of body, followed by a line
+// counting the lines left out. A trailing newline does not count as a line.
+func truncateLines(body string, maxLines int) string {
+	lines := strings.Split(strings.TrimSuffix(body, "\n"), "\n")
+	if len(lines) <= maxLines {
+		return body
+	}
+
+	omitted := len(lines) - maxLines
+	return strings.Join(lines[:maxLines], "\n") + fmt.Sprintf("\n%s (%d more lines)", truncationMarker, omitted)
+}
+
+// baseURLs returns the servers to try for a send, in order: the primary base URL
+// followed by the failover URLs. With load balancing, the node pool is rotated
+// so that each send starts at the next node and falls back to the others.
+func (c *Client) baseURLs() []string {
+	if len(c.balancedURLs) == 0 {
+		return append([]string{c.baseURL}, c.failoverURLs...)
+	}
+
+	start := int((c.balanceNext.Add(1) - 1) % uint64(len(c.balancedURLs)))
+	urls := make([]string, 0, len(c.balancedURLs)+len(c.failoverURLs))
+	urls = append(urls, c.balancedURLs[start:]...)
+	urls = append(urls, c.balancedURLs[:start]...)
+	return append(urls, c.failoverURLs...)
+}
+
+// shouldFailover reports whether a failed request should be retried against
+// the next server. Only transport errors (statusCode 0) and 5xx responses
+// trigger a failover, and never once the context is done.
+func shouldFailover(ctx context.Context, statusCode int) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	return statusCode == 0 || statusCode >= http.StatusInternalServerError
+}
+
+// newRequest creates the HTTP request that delivers n to the device identified by key
+// through the server at baseURL.
+// By default the notification is encoded in the URL of a GET request;
+// in POST mode, or if it carries image data, it is sent as a JSON payload to the /push endpoint.
+func (c *Client) newRequest(ctx context.Context, baseURL, key string, n *Notification) (*http.Request, error) {
+	// With WithKeyInHeader, the key is left out of the URL and payload
+	notificationKey := key
+	if c.keyHeader != "" {
+		notificationKey = ""
+	}
+
+	var (
+		req *http.Request
+		err error
+	)
+	if !c.postMode && n.image == "" {
+		apiURL := c.buildNotificationURL(baseURL, notificationKey, n)
+		if c.maxURLLength > 0 && len(apiURL) > c.maxURLLength {
+			return nil, &permanentError{err: fmt.Errorf("%w: %d bytes, limit %d", ErrURLTooLong, len(apiURL), c.maxURLLength)}
+		}
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+	} else {
+		req, err = c.newPostRequest(ctx, baseURL, c.buildPayload(notificationKey, n))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.keyHeader != "" {
+		req.Header.Set(c.keyHeader, key)
+	}
+	return req, nil
+}
+
+// newPostRequest creates a POST request that sends payload as JSON, or as a form
+// with WithFormEncoded, to the /push endpoint of the server at baseURL.
+func (c *Client) newPostRequest(ctx context.Context, baseURL string, payload map[string]interface{}) (*http.Request, error) {
+	contentType := "application/json; charset=utf-8"
+	var (
+		data []byte
+		err  error
+	)
+	if c.formEncoded {
+		contentType = "application/x-www-form-urlencoded"
+		data = []byte(encodeForm(payload).Encode())
+	} else if data, err = json.Marshal(payload); err != nil {
+		return nil, fmt.Errorf("failed to encode payload: %w", err)
+	}
+
+	// Compress payloads large enough for compression to pay off
+	compressed := c.gzipThreshold > 0 && len(data) > c.gzipThreshold
+	if compressed {
+		if data, err = gzipBytes(data); err != nil {
+			return nil, fmt.Errorf("failed to compress payload: %w", err)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, joinURL(baseURL, "push"), bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	if compressed {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	return req, nil
+}
+
+// encodeForm converts payload to form values. A list of keys, as sent
+// to several devices, becomes a repeated field.
+func encodeForm(payload map[string]interface{}) url.Values {
+	form := url.Values{}
+	for name, value := range payload {
+		switch v := value.(type) {
+		case []string:
+			form[name] = v
+		default:
+			form.Set(name, fmt.Sprint(v))
+		}
+	}
+	return form
+}
+
+// gzipBytes returns data compressed with gzip.
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// buildPayload constructs the JSON payload fields used in POST mode.
+// The device field is omitted if key is empty.
+func (c *Client) buildPayload(key string, n *Notification) map[string]interface{} {
+	payload := map[string]interface{}{
+		"body": n.body,
+	}
+	if key != "" {
+		payload[string(c.deviceField)] = key
+	}
+	if n.title != "" {
+		payload["title"] = n.title
+	}
+	if n.subtitle != "" {
+		payload["subtitle"] = n.subtitle
+	}
+	for name, values := range n.params() {
+		payload[name] = values[0]
+	}
+
+	return payload
+}
+
+// do sends req and returns the response status code.
+// The status code is 0 if no response was received.
+func (c *Client) do(req *http.Request) (int, error) {
+	ctx := req.Context()
+	observed := c.observedRequest(req)
+	c.observer.BeforeSend(ctx, observed)
+	if sink := requestSinkFrom(ctx); sink != nil {
+		sink(req)
+	}
+
+	sentAt := time.Now()
+	traced, report := c.withTrace(req, sentAt)
+	resp, err := c.client.Do(traced)
+	report()
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		err = fmt.Errorf("failed to send request: %w", c.redactError(err, req))
+		c.recordSend(req, 0, err, sentAt)
+		c.observer.AfterSend(ctx, nil, err)
+		return 0, err
 	}
 	defer resp.Body.Close()
 
+	if c.responseHeaderCallback != nil {
+		c.responseHeaderCallback(resp.Header)
+	}
+
+	sink := responseSinkFrom(ctx)
+	var body []byte
+	checkBody := resp.StatusCode == http.StatusOK && (c.retryableBodyCodes != nil || c.successBodyCodes != nil)
+	if sink != nil || checkBody || (resp.StatusCode != http.StatusOK && c.errorResponseLogger != nil) {
+		body, _ = io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	}
+	if checkBody {
+		err = c.checkBodyCode(body)
+	}
+
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		err = fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		if delay, ok := parseRetryAfter(resp.Header.Get("Retry-After"), c.now()); ok {
+			err = &retryAfterError{err: err, delay: delay}
+		}
+		if c.errorResponseLogger != nil {
+			c.errorResponseLogger(resp.StatusCode, body[:min(len(body), maxErrorResponseBytes)])
+		}
+	}
+	if sink != nil {
+		sink(resp.StatusCode, body)
 	}
+	c.recordSend(req, resp.StatusCode, err, sentAt)
+	c.observer.AfterSend(ctx, c.observedResponse(resp, observed), err)
 
-	return nil
+	return resp.StatusCode, err
+}
+
+// requestSinkKey is the context key of the function receiving sent requests.
+type requestSinkKey struct{}
+
+// withRequestSink returns a context that makes do pass every request to sink
+// just before sending it.
+func withRequestSink(ctx context.Context, sink func(req *http.Request)) context.Context {
+	return context.WithValue(ctx, requestSinkKey{}, sink)
+}
+
+// requestSinkFrom returns the request sink of ctx, or nil if it has none.
+func requestSinkFrom(ctx context.Context) func(req *http.Request) {
+	sink, _ := ctx.Value(requestSinkKey{}).(func(req *http.Request))
+	return sink
+}
+
+// responseSinkKey is the context key of the function receiving response bodies.
+type responseSinkKey struct{}
+
+// withResponseSink returns a context that makes do pass the status code and body
+// of every response it receives to sink.
+func withResponseSink(ctx context.Context, sink func(status int, body []byte)) context.Context {
+	return context.WithValue(ctx, responseSinkKey{}, sink)
+}
+
+// responseSinkFrom returns the response sink of ctx, or nil if it has none.
+func responseSinkFrom(ctx context.Context) func(status int, body []byte) {
+	sink, _ := ctx.Value(responseSinkKey{}).(func(status int, body []byte))
+	return sink
+}
+
+// recordSend reports the outcome of req to the metrics observer and adds it
+// to the send history, if any.
+func (c *Client) recordSend(req *http.Request, statusCode int, err error, sentAt time.Time) {
+	if c.metrics != nil {
+		c.metrics.ObserveSend(req.Context(), SendMetrics{
+			StatusCode: statusCode,
+			Err:        err,
+			Duration:   time.Since(sentAt),
+			Size:       payloadSize(req),
+		})
+	}
+
+	if c.history == nil {
+		return
+	}
+	c.history.add(SendRecord{URL: c.observedURL(req), StatusCode: statusCode, Err: err, Time: sentAt})
+}
+
+// SendError sends err as a time-sensitive notification.
+// The body is set to the error message and the subtitle to the error's type name.
+// Additional options can be provided to override these defaults.
+func (c *Client) SendError(ctx context.Context, err error, opts ...Option) error {
+	if err == nil {
+		return fmt.Errorf("error is required")
+	}
+
+	opts = append([]Option{WithSubtitle(errorTypeName(err)), WithTimeSensitive()}, opts...)
+	return c.Send(ctx, err.Error(), opts...)
+}
+
+// SendJSON sends v marshaled as indented JSON as the notification body.
+// Additional options can be provided to customize the notification.
+func (c *Client) SendJSON(ctx context.Context, v interface{}, opts ...Option) error {
+	body, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal body: %w", err)
+	}
+
+	return c.Send(ctx, string(body), opts...)
+}
+
+// errorTypeName returns the name of the error's type without the package path or pointer marker.
+func errorTypeName(err error) string {
+	t := reflect.TypeOf(err)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Name() == "" {
+		return "error"
+	}
+	return t.Name()
 }
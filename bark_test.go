@@ -105,7 +105,7 @@ func TestBuildNotificationURL(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			n := &notification{
+			n := &Notification{
 				title: defaultTitle,
 				body:  tt.body,
 			}
@@ -113,7 +113,7 @@ func TestBuildNotificationURL(t *testing.T) {
 				opt(n)
 			}
 
-			urlPath := client.buildNotificationURL(n)
+			urlPath := client.buildNotificationURL(client.baseURL, client.key, n)
 
 			// For query parameters, the order might be different, so we need to check differently
 			if strings.Contains(tt.wantPath, "?") {
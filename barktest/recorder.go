@@ -0,0 +1,201 @@
+// Package barktest provides utilities for testing code that sends Bark notifications.
+package barktest
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// defaultResponseBody is the response returned by a Bark server for a successful push.
+const defaultResponseBody = `{"code":200,"message":"success"}`
+
+// RecordedRequest represents a notification request captured by RecordingTransport.
+type RecordedRequest struct {
+	Method   string
+	URL      string
+	Header   http.Header
+	Key      string
+	Title    string
+	Subtitle string
+	Body     string
+	// Keys holds the device keys of a batch request sent to /push.
+	Keys []string
+	// Params holds the remaining notification parameters, such as sound or level.
+	Params map[string]string
+}
+
+// RecordingTransport is an http.RoundTripper that records every notification
+// request without contacting a server and replies with a canned response.
+// The zero value is ready to use and replies with a successful Bark response.
+type RecordingTransport struct {
+	// StatusCode is the status code of the canned response. Defaults to 200.
+	StatusCode int
+	// ResponseBody is the body of the canned response. Defaults to a Bark success response.
+	ResponseBody string
+
+	mu       sync.Mutex
+	requests []RecordedRequest
+}
+
+// RoundTrip records req and returns the canned response.
+func (t *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, err := parseRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	t.requests = append(t.requests, recorded)
+	statusCode, body := t.StatusCode, t.ResponseBody
+	t.mu.Unlock()
+
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	if body == "" {
+		body = defaultResponseBody
+	}
+
+	return &http.Response{
+		StatusCode: statusCode,
+		Status:     fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		Header:     http.Header{"Content-Type": []string{"application/json; charset=utf-8"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+// Requests returns the requests recorded so far, in the order they were sent.
+func (t *RecordingTransport) Requests() []RecordedRequest {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]RecordedRequest(nil), t.requests...)
+}
+
+// parseRequest extracts the notification carried by req.
+func parseRequest(req *http.Request) (RecordedRequest, error) {
+	recorded := RecordedRequest{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Header: req.Header.Clone(),
+		Params: map[string]string{},
+	}
+
+	if req.Method == http.MethodPost {
+		return recorded, parsePayload(req, &recorded)
+	}
+
+	// GET requests encode the notification as /key/[title/[subtitle/]]body
+	segments := strings.Split(strings.Trim(req.URL.EscapedPath(), "/"), "/")
+	for i, segment := range segments {
+		unescaped, err := url.PathUnescape(segment)
+		if err != nil {
+			return recorded, fmt.Errorf("barktest: invalid path segment %q: %w", segment, err)
+		}
+		segments[i] = unescaped
+	}
+
+	recorded.Key = segments[0]
+	switch len(segments) {
+	case 2:
+		recorded.Body = segments[1]
+	case 3:
+		recorded.Title, recorded.Body = segments[1], segments[2]
+	case 4:
+		recorded.Title, recorded.Subtitle, recorded.Body = segments[1], segments[2], segments[3]
+	}
+
+	for name, values := range req.URL.Query() {
+		recorded.Params[name] = values[0]
+	}
+
+	return recorded, nil
+}
+
+// parsePayload extracts the notification from the JSON payload of a POST request.
+func parsePayload(req *http.Request, recorded *RecordedRequest) error {
+	if req.Body == nil {
+		return nil
+	}
+	defer req.Body.Close()
+
+	body := io.Reader(req.Body)
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(req.Body)
+		if err != nil {
+			return fmt.Errorf("barktest: invalid gzip payload: %w", err)
+		}
+		defer zr.Close()
+		body = zr
+	}
+
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		return parseForm(body, recorded)
+	}
+
+	var payload map[string]interface{}
+	if err := json.NewDecoder(body).Decode(&payload); err != nil {
+		return fmt.Errorf("barktest: invalid payload: %w", err)
+	}
+
+	for name, value := range payload {
+		str := fmt.Sprint(value)
+		switch name {
+		case "device_key", "device_token":
+			recorded.Key = str
+		case "title":
+			recorded.Title = str
+		case "subtitle":
+			recorded.Subtitle = str
+		case "body":
+			recorded.Body = str
+		case "device_keys":
+			keys, _ := value.([]interface{})
+			for _, key := range keys {
+				recorded.Keys = append(recorded.Keys, fmt.Sprint(key))
+			}
+		default:
+			recorded.Params[name] = str
+		}
+	}
+
+	return nil
+}
+
+// parseForm extracts the notification from the form-encoded payload of a POST request.
+func parseForm(body io.Reader, recorded *RecordedRequest) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return fmt.Errorf("barktest: failed to read payload: %w", err)
+	}
+	form, err := url.ParseQuery(string(data))
+	if err != nil {
+		return fmt.Errorf("barktest: invalid form payload: %w", err)
+	}
+
+	for name, values := range form {
+		switch name {
+		case "device_key", "device_token":
+			recorded.Key = values[0]
+		case "title":
+			recorded.Title = values[0]
+		case "subtitle":
+			recorded.Subtitle = values[0]
+		case "body":
+			recorded.Body = values[0]
+		case "device_keys":
+			recorded.Keys = values
+		default:
+			recorded.Params[name] = values[0]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package barktest_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/xpzouying/gobark"
+	"github.com/xpzouying/gobark/barktest"
+)
+
+func TestRecordingTransport(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []gobark.ClientOption
+		wantMethod string
+	}{
+		{
+			name:       "GET mode",
+			wantMethod: http.MethodGet,
+		},
+		{
+			name:       "POST mode",
+			opts:       []gobark.ClientOption{gobark.WithPostMode()},
+			wantMethod: http.MethodPost,
+		},
+		{
+			name:       "form-encoded POST mode",
+			opts:       []gobark.ClientOption{gobark.WithFormEncoded()},
+			wantMethod: http.MethodPost,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &barktest.RecordingTransport{}
+			opts := append([]gobark.ClientOption{gobark.WithHTTPClient(&http.Client{Transport: transport})}, tt.opts...)
+			client, err := gobark.NewClient("https://bark.example.com", "test-key", opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = client.Send(context.Background(), "Line 1\nLine 2",
+				gobark.WithTitle("Deploy"),
+				gobark.WithSubtitle("api/v2"),
+				gobark.WithSound("bell"),
+			)
+			if err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			requests := transport.Requests()
+			if len(requests) != 1 {
+				t.Fatalf("recorded %d requests, want 1", len(requests))
+			}
+
+			got := requests[0]
+			if got.Method != tt.wantMethod {
+				t.Errorf("Method = %q, want %q", got.Method, tt.wantMethod)
+			}
+			if got.Key != "test-key" {
+				t.Errorf("Key = %q, want %q", got.Key, "test-key")
+			}
+			if got.Title != "Deploy" {
+				t.Errorf("Title = %q, want %q", got.Title, "Deploy")
+			}
+			if got.Subtitle != "api/v2" {
+				t.Errorf("Subtitle = %q, want %q", got.Subtitle, "api/v2")
+			}
+			if got.Body != "Line 1\nLine 2" {
+				t.Errorf("Body = %q, want %q", got.Body, "Line 1\nLine 2")
+			}
+			if got.Params["sound"] != "bell" {
+				t.Errorf("Params[sound] = %q, want %q", got.Params["sound"], "bell")
+			}
+		})
+	}
+}
+
+func TestRecordingTransport_CannedResponse(t *testing.T) {
+	transport := &barktest.RecordingTransport{StatusCode: http.StatusInternalServerError}
+	client, err := gobark.NewClient("https://bark.example.com", "test-key",
+		gobark.WithHTTPClient(&http.Client{Transport: transport}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Send(context.Background(), "test message"); err == nil {
+		t.Error("Send() error = nil, want error")
+	}
+	if len(transport.Requests()) != 1 {
+		t.Errorf("recorded %d requests, want 1", len(transport.Requests()))
+	}
+}
@@ -0,0 +1,191 @@
+package gobark
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// defaultBatchSize is the maximum number of keys SendToKeys puts in one request
+// unless configured with WithBatchSize.
+const defaultBatchSize = 100
+
+// WithBatchSize sets the maximum number of keys SendToKeys puts in one request.
+func WithBatchSize(n int) ClientOption {
+	return func(c *Client) error {
+		if n <= 0 {
+			return fmt.Errorf("batch size must be positive")
+		}
+		c.batchSize = n
+		return nil
+	}
+}
+
+// SendToKeys sends the same notification to every device in keys.
+// The keys are split into chunks of the batch size, and each chunk is sent
+// as one POST request listing its keys in the device_keys field of the payload.
+// Chunks are sent in order; a failed chunk does not stop the remaining ones,
+// and the errors of all failed chunks are returned joined together.
+// Notifications below the minimum level fail with ErrBelowMinLevel, as with Send.
+// Notifications are not deduplicated, since the same content may go to new
+// devices, nor passed to the dead letter handler.
+func (c *Client) SendToKeys(ctx context.Context, keys []string, body string, opts ...Option) error {
+	return c.sendToKeys(ctx, keys, body, opts, nil)
+}
+
+// SendBatch sends the same notification to every device in keys like SendToKeys,
+// and returns the per-device results reported by the server for the chunks that
+// were delivered. The results are parsed from the data field of each response:
+//
+//	{"code": 200, "message": "success", "data": [
+//	    {"device_key": "key1", "code": 200, "message": "success"},
+//	    {"device_key": "key2", "code": 400, "message": "invalid device key"}
+//	]}
+//
+// A chunk whose response cannot be parsed is reported as an error.
+func (c *Client) SendBatch(ctx context.Context, keys []string, body string, opts ...Option) (*BatchResponse, error) {
+	var (
+		mu       sync.Mutex
+		response BatchResponse
+		errs     []error
+	)
+	err := c.sendToKeys(ctx, keys, body, opts, func(status int, data []byte) {
+		if status != http.StatusOK {
+			return
+		}
+		parsed, err := ParseBatchResponse(data)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		response.Results = append(response.Results, parsed.Results...)
+	})
+
+	return &response, errors.Join(append([]error{err}, errs...)...)
+}
+
+// BatchResponse holds the per-device results of a batch sent with SendBatch.
+type BatchResponse struct {
+	Results []BatchResult `json:"data"`
+}
+
+// BatchResult is the result of delivering a batch notification to one device.
+type BatchResult struct {
+	Key     string `json:"device_key"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// ParseBatchResponse parses the body of a server response to a batch request.
+func ParseBatchResponse(data []byte) (*BatchResponse, error) {
+	var response BatchResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse batch response: %w", err)
+	}
+	return &response, nil
+}
+
+// Succeeded returns the results of the devices the notification was delivered to.
+func (r *BatchResponse) Succeeded() []BatchResult {
+	return r.filter(true)
+}
+
+// Failed returns the results of the devices the notification could not be delivered to.
+func (r *BatchResponse) Failed() []BatchResult {
+	return r.filter(false)
+}
+
+// filter returns the results whose success matches succeeded.
+func (r *BatchResponse) filter(succeeded bool) []BatchResult {
+	var results []BatchResult
+	for _, result := range r.Results {
+		if (result.Code == http.StatusOK) == succeeded {
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// sendToKeys sends the notification for body and opts to keys in chunks,
+// passing the response to each chunk to sink if it is not nil.
+func (c *Client) sendToKeys(ctx context.Context, keys []string, body string, opts []Option, sink func(status int, body []byte)) error {
+	if len(keys) == 0 {
+		return fmt.Errorf("at least one key is required")
+	}
+
+	n, err := c.newNotification(body, opts)
+	if err != nil {
+		return err
+	}
+	if c.belowMinLevel(n) {
+		return ErrBelowMinLevel
+	}
+	if err := c.runProbe(ctx); err != nil {
+		return err
+	}
+	c.resolveSound(ctx, n)
+	if err := c.checkSound(ctx, n); err != nil {
+		return err
+	}
+
+	if sink != nil {
+		ctx = withResponseSink(ctx, sink)
+	}
+
+	batchSize := c.batchSize
+	if batchSize == 0 {
+		batchSize = defaultBatchSize
+	}
+
+	var errs []error
+	for i, chunk := range chunkKeys(keys, batchSize) {
+		if err := c.sendBatch(ctx, n, chunk); err != nil {
+			errs = append(errs, fmt.Errorf("batch %d: %w", i, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// sendBatch sends n to the devices identified by keys in a single request.
+func (c *Client) sendBatch(ctx context.Context, n *Notification, keys []string) error {
+	// Wait for the group's rate limiter, if any, before sending
+	if limiter, ok := c.groupLimiters[n.group]; ok {
+		if err := limiter.Wait(ctx); err != nil {
+			return fmt.Errorf("group %q rate limited: %w", n.group, err)
+		}
+	}
+
+	release, err := c.acquireSendSlot(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	ctx = c.withRequestID(ctx, n)
+	payload := c.buildPayload("", n)
+	payload["device_keys"] = keys
+
+	_, err = c.retry(ctx, func(ctx context.Context) (int, error) {
+		return c.sendOnce(ctx, n, func(ctx context.Context, baseURL string) (*http.Request, error) {
+			return c.newPostRequest(ctx, baseURL, payload)
+		})
+	})
+	return err
+}
+
+// chunkKeys splits keys into consecutive chunks of at most size keys.
+func chunkKeys(keys []string, size int) [][]string {
+	chunks := make([][]string, 0, (len(keys)+size-1)/size)
+	for len(keys) > size {
+		chunks = append(chunks, keys[:size:size])
+		keys = keys[size:]
+	}
+	return append(chunks, keys)
+}
@@ -0,0 +1,198 @@
+package gobark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xpzouying/gobark/barktest"
+	"golang.org/x/time/rate"
+)
+
+func TestSendToKeys_Batches(t *testing.T) {
+	client, transport := newRecordingClient(t, WithBatchSize(50))
+
+	keys := make([]string, 250)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+
+	if err := client.SendToKeys(context.Background(), keys, "maintenance tonight", WithTitle("Ops")); err != nil {
+		t.Fatalf("SendToKeys() error = %v", err)
+	}
+
+	requests := transport.Requests()
+	if len(requests) != 5 {
+		t.Fatalf("sent %d requests, want 5", len(requests))
+	}
+
+	var sent []string
+	for i, req := range requests {
+		if req.Method != http.MethodPost {
+			t.Errorf("request %d method = %s, want POST", i, req.Method)
+		}
+		if len(req.Keys) != 50 {
+			t.Errorf("request %d has %d keys, want 50", i, len(req.Keys))
+		}
+		if req.Title != "Ops" || req.Body != "maintenance tonight" {
+			t.Errorf("request %d notification = %q/%q, want Ops/maintenance tonight", i, req.Title, req.Body)
+		}
+		sent = append(sent, req.Keys...)
+	}
+	for i, key := range keys {
+		if sent[i] != key {
+			t.Fatalf("key %d = %q, want %q", i, sent[i], key)
+		}
+	}
+}
+
+func TestSendToKeys_ContinuesPastFailedBatch(t *testing.T) {
+	transport := &failingBatchTransport{failBatch: 1}
+	client, err := NewClient("", "test-key", WithHTTPClient(&http.Client{Transport: transport}), WithBatchSize(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = client.SendToKeys(context.Background(), []string{"a", "b", "c", "d", "e"}, "test message")
+	if err == nil {
+		t.Fatal("SendToKeys() error = nil, want error")
+	}
+	if transport.calls != 3 {
+		t.Errorf("sent %d requests, want 3", transport.calls)
+	}
+}
+
+func TestSendToKeys_NoKeys(t *testing.T) {
+	client, _ := newRecordingClient(t)
+
+	if err := client.SendToKeys(context.Background(), nil, "test message"); err == nil {
+		t.Error("SendToKeys() error = nil, want error")
+	}
+}
+
+func TestChunkKeys(t *testing.T) {
+	chunks := chunkKeys([]string{"a", "b", "c", "d", "e"}, 2)
+	if got := fmt.Sprint(chunks); got != "[[a b] [c d] [e]]" {
+		t.Errorf("chunkKeys() = %v, want [[a b] [c d] [e]]", got)
+	}
+}
+
+// failingBatchTransport fails the request with index failBatch and accepts the others.
+type failingBatchTransport struct {
+	recorder  barktest.RecordingTransport
+	failBatch int
+	calls     int
+}
+
+func (t *failingBatchTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	defer func() { t.calls++ }()
+	if t.calls == t.failBatch {
+		return nil, fmt.Errorf("connection reset")
+	}
+	return t.recorder.RoundTrip(req)
+}
+
+// sampleBatchResponse is a batch response reporting one failed device out of three.
+const sampleBatchResponse = `{"code":200,"message":"success","data":[
+	{"device_key":"a","code":200,"message":"success"},
+	{"device_key":"b","code":400,"message":"invalid device key"},
+	{"device_key":"c","code":200,"message":"success"}
+]}`
+
+func TestParseBatchResponse(t *testing.T) {
+	response, err := ParseBatchResponse([]byte(sampleBatchResponse))
+	if err != nil {
+		t.Fatalf("ParseBatchResponse() error = %v", err)
+	}
+
+	if got := fmt.Sprint(response.Succeeded()); got != "[{a 200 success} {c 200 success}]" {
+		t.Errorf("Succeeded() = %s, want devices a and c", got)
+	}
+	if got := fmt.Sprint(response.Failed()); got != "[{b 400 invalid device key}]" {
+		t.Errorf("Failed() = %s, want device b", got)
+	}
+
+	if _, err := ParseBatchResponse([]byte("<html>")); err == nil {
+		t.Error("ParseBatchResponse() of invalid body error = nil, want error")
+	}
+}
+
+func TestSendBatch(t *testing.T) {
+	client, transport := newRecordingClient(t)
+	transport.ResponseBody = sampleBatchResponse
+
+	response, err := client.SendBatch(context.Background(), []string{"a", "b", "c"}, "maintenance tonight")
+	if err != nil {
+		t.Fatalf("SendBatch() error = %v", err)
+	}
+
+	if len(response.Results) != 3 {
+		t.Fatalf("got %d results, want 3", len(response.Results))
+	}
+	if failed := response.Failed(); len(failed) != 1 || failed[0].Key != "b" {
+		t.Errorf("Failed() = %v, want device b", failed)
+	}
+}
+
+func TestSendToKeys_SendChecks(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []ClientOption
+		sound   string
+		level   Option
+		wantErr error
+	}{
+		{name: "below minimum level", opts: []ClientOption{WithMinLevel(LevelCritical)}, level: WithPassive(), wantErr: ErrBelowMinLevel},
+		{name: "unknown sound", opts: []ClientOption{WithStrictSounds()}, sound: "custom-chime", wantErr: ErrUnknownSound},
+		{name: "listed sound", opts: []ClientOption{WithStrictSounds()}, sound: "bell"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, sent, _ := newSoundServer(t, []string{"alarm", "bell"})
+			client, err := NewClient(server.URL, "test-key", tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			opts := []Option{WithSound(tt.sound)}
+			if tt.level != nil {
+				opts = append(opts, tt.level)
+			}
+			keys := []string{"key-1", "key-2"}
+			if err := client.SendToKeys(context.Background(), keys, "maintenance tonight", opts...); !errors.Is(err, tt.wantErr) {
+				t.Errorf("SendToKeys() error = %v, want %v", err, tt.wantErr)
+			}
+			wantSent := 1
+			if tt.wantErr != nil {
+				wantSent = 0
+				if _, err := client.SendBatch(context.Background(), keys, "maintenance tonight", opts...); !errors.Is(err, tt.wantErr) {
+					t.Errorf("SendBatch() error = %v, want %v", err, tt.wantErr)
+				}
+			}
+			if len(*sent) != wantSent {
+				t.Errorf("sent %d requests, want %d", len(*sent), wantSent)
+			}
+		})
+	}
+}
+
+func TestSendToKeys_GroupRateLimit(t *testing.T) {
+	client, transport := newRecordingClient(t, WithBatchSize(1), WithGroupRateLimit("backup", rate.Every(time.Minute)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// The first batch uses the limiter's only token, so the second one is throttled
+	err := client.SendToKeys(ctx, []string{"key-1", "key-2"}, "backup started", WithGroup("backup"))
+	if err == nil || !strings.Contains(err.Error(), "rate limited") {
+		t.Errorf("SendToKeys() error = %v, want the second batch rate limited", err)
+	}
+	if n := len(transport.Requests()); n != 1 {
+		t.Errorf("sent %d batches, want 1", n)
+	}
+}
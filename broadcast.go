@@ -0,0 +1,121 @@
+package gobark
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// defaultBroadcastConcurrency is the maximum number of notifications SendAll
+// sends at once unless configured with WithBroadcastConcurrency.
+const defaultBroadcastConcurrency = 10
+
+// WithBroadcastConcurrency sets the maximum number of notifications SendAll and
+// SendToKeysStream send at once, trading throughput for server load. The default is 10.
+func WithBroadcastConcurrency(n int) ClientOption {
+	return func(c *Client) error {
+		if n <= 0 {
+			return fmt.Errorf("broadcast concurrency must be positive")
+		}
+		c.broadcastConcurrency = n
+		return nil
+	}
+}
+
+// BroadcastError is returned when some of the notifications sent together fail.
+// The notifications that did not fail were delivered.
+type BroadcastError struct {
+	// Total is the number of notifications that were sent.
+	Total int
+	// Failures holds one entry per failed notification, ordered by index.
+	Failures []BroadcastFailure
+}
+
+// BroadcastFailure describes a notification that could not be delivered.
+type BroadcastFailure struct {
+	// Index is the position of the notification in the list given to SendAll.
+	Index int
+	// Err is the error that made the send fail.
+	Err error
+}
+
+func (e *BroadcastError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d of %d notifications failed", len(e.Failures), e.Total)
+	for _, failure := range e.Failures {
+		fmt.Fprintf(&b, "; notification %d: %v", failure.Index, failure.Err)
+	}
+	return b.String()
+}
+
+// Unwrap returns the errors of the failed notifications, so that errors.Is
+// and errors.As match any of them.
+func (e *BroadcastError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, failure := range e.Failures {
+		errs[i] = failure.Err
+	}
+	return errs
+}
+
+// SendAll sends every notification in notifs concurrently, with at most the
+// broadcast concurrency in flight at once, and with the client
+// defaults applied to each. A failed notification does not stop the others:
+// once all have been attempted, the failures are reported in a *BroadcastError.
+// Notifications are not passed to the dead letter handler, which only receives
+// notifications sent with Send.
+func (c *Client) SendAll(ctx context.Context, notifs []*Notification) error {
+	errs := make([]error, len(notifs))
+
+	concurrency := c.broadcastConcurrency
+	if concurrency == 0 {
+		concurrency = defaultBroadcastConcurrency
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(len(notifs), concurrency); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				func() {
+					defer recoverPanic(&errs[i])
+					errs[i] = c.sendNotification(ctx, notifs[i])
+				}()
+			}
+		}()
+	}
+	for i := range notifs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	broadcastErr := &BroadcastError{Total: len(notifs)}
+	for i, err := range errs {
+		if err != nil {
+			broadcastErr.Failures = append(broadcastErr.Failures, BroadcastFailure{Index: i, Err: err})
+		}
+	}
+	if len(broadcastErr.Failures) > 0 {
+		return broadcastErr
+	}
+	return nil
+}
+
+// sendNotification prepares and delivers a notification built with NewNotification.
+func (c *Client) sendNotification(ctx context.Context, orig *Notification) error {
+	if orig == nil {
+		return fmt.Errorf("notification must not be nil")
+	}
+
+	n, err := c.prepare(orig)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.deliver(ctx, n)
+	return err
+}
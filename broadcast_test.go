@@ -0,0 +1,108 @@
+package gobark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSendAll_ReportsFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/disk full") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "test-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = client.SendAll(context.Background(), []*Notification{
+		NewNotification("backup done", WithTitle("db01")),
+		NewNotification("disk full", WithTitle("db02"), WithTimeSensitive()),
+		NewNotification("deploy finished", WithGroup("ci")),
+	})
+
+	var broadcastErr *BroadcastError
+	if !errors.As(err, &broadcastErr) {
+		t.Fatalf("SendAll() error = %v, want *BroadcastError", err)
+	}
+	if broadcastErr.Total != 3 {
+		t.Errorf("Total = %d, want 3", broadcastErr.Total)
+	}
+	if len(broadcastErr.Failures) != 1 {
+		t.Fatalf("got %d failures, want 1: %v", len(broadcastErr.Failures), err)
+	}
+	if failure := broadcastErr.Failures[0]; failure.Index != 1 || failure.Err.Error() != "unexpected status code: 400" {
+		t.Errorf("failure = {%d, %v}, want {1, unexpected status code: 400}", failure.Index, failure.Err)
+	}
+}
+
+func TestSendAll_AllSucceed(t *testing.T) {
+	client, transport := newRecordingClient(t, WithDefaultSubtitle("prod"))
+
+	notifs := []*Notification{
+		NewNotification("one"),
+		NewNotification("two"),
+	}
+	if err := client.SendAll(context.Background(), notifs); err != nil {
+		t.Fatalf("SendAll() error = %v", err)
+	}
+
+	requests := transport.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("sent %d requests, want 2", len(requests))
+	}
+	for _, req := range requests {
+		if req.Subtitle != "prod" {
+			t.Errorf("subtitle = %q, want client default %q", req.Subtitle, "prod")
+		}
+	}
+	if notifs[0].subtitle != "" {
+		t.Errorf("SendAll() modified the notification subtitle to %q", notifs[0].subtitle)
+	}
+}
+
+func TestWithBroadcastConcurrency(t *testing.T) {
+	var inFlight, maxInFlight int32
+	transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			peak := atomic.LoadInt32(&maxInFlight)
+			if n <= peak || atomic.CompareAndSwapInt32(&maxInFlight, peak, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+
+	client, err := NewClient("https://bark.example.com", "test-key",
+		WithHTTPClient(&http.Client{Transport: transport}), WithBroadcastConcurrency(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notifs := make([]*Notification, 50)
+	for i := range notifs {
+		notifs[i] = NewNotification(fmt.Sprintf("message %d", i))
+	}
+	if err := client.SendAll(context.Background(), notifs); err != nil {
+		t.Fatalf("SendAll() error = %v", err)
+	}
+
+	if got := atomic.LoadInt32(&maxInFlight); got > 3 {
+		t.Errorf("max in-flight sends = %d, want at most 3", got)
+	}
+}
@@ -0,0 +1,509 @@
+package gobark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/time/rate"
+)
+
+// ClientOption represents a function that configures the Client.
+type ClientOption func(*Client) error
+
+// WithHTTPClient sets the HTTP client used to send requests.
+// This can be used to configure timeouts, proxies or a custom transport.
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) error {
+		if httpClient == nil {
+			return fmt.Errorf("http client must not be nil")
+		}
+		c.client = httpClient
+		return nil
+	}
+}
+
+// WithPostMode sends notifications as a JSON payload to the server's /push
+// endpoint instead of encoding them in the URL of a GET request.
+// This avoids URL length limits for long notification content.
+func WithPostMode() ClientOption {
+	return func(c *Client) error {
+		c.postMode = true
+		return nil
+	}
+}
+
+// WithFormEncoded sends notifications to the server's /push endpoint like
+// WithPostMode, but as an application/x-www-form-urlencoded form instead of JSON,
+// for proxies that only accept form posts.
+func WithFormEncoded() ClientOption {
+	return func(c *Client) error {
+		c.postMode = true
+		c.formEncoded = true
+		return nil
+	}
+}
+
+// API versions of the Bark server accepted by WithAPIVersion.
+const (
+	// APIV1 encodes notifications in the URL path of GET requests, as in
+	// /key/title/body?sound=bell. It is supported by every Bark server and is the default.
+	APIV1 = "v1"
+	// APIV2 sends notifications as a JSON payload to the /push endpoint,
+	// with the key in the device_key field. It is the same as WithPostMode.
+	APIV2 = "v2"
+)
+
+// WithAPIVersion sets the version of the Bark server API used to send notifications,
+// either APIV1 or APIV2. The default is APIV1.
+func WithAPIVersion(v string) ClientOption {
+	return func(c *Client) error {
+		switch v {
+		case APIV1:
+			c.postMode = false
+		case APIV2:
+			c.postMode = true
+		default:
+			return fmt.Errorf("unsupported api version %q", v)
+		}
+		return nil
+	}
+}
+
+// DeviceField names the POST payload field that identifies the target device.
+type DeviceField string
+
+const (
+	// DeviceKeyField identifies the device by its Bark key. This is the default.
+	DeviceKeyField DeviceField = "device_key"
+	// DeviceTokenField identifies the device by its APNs device token,
+	// as expected by self-hosted servers that push to raw tokens.
+	DeviceTokenField DeviceField = "device_token"
+)
+
+// WithDeviceField sets which payload field carries the key in POST mode.
+// It has no effect on GET requests, which always carry the key in the URL path.
+func WithDeviceField(field DeviceField) ClientOption {
+	return func(c *Client) error {
+		if field != DeviceKeyField && field != DeviceTokenField {
+			return fmt.Errorf("unsupported device field %q", field)
+		}
+		c.deviceField = field
+		return nil
+	}
+}
+
+// WithAutoGzip compresses POST payloads larger than threshold bytes with gzip.
+// Smaller payloads are sent uncompressed, since compressing them wastes CPU for no gain.
+// It only applies in POST mode; the target server must accept gzip-encoded request bodies.
+func WithAutoGzip(threshold int) ClientOption {
+	return func(c *Client) error {
+		if threshold <= 0 {
+			return fmt.Errorf("gzip threshold must be positive")
+		}
+		c.gzipThreshold = threshold
+		return nil
+	}
+}
+
+// WithFailoverURLs sets backup base URLs that are tried in order when the
+// primary server cannot be reached or responds with a 5xx status.
+// Client errors (4xx) are returned immediately without trying the backups.
+func WithFailoverURLs(urls ...string) ClientOption {
+	return func(c *Client) error {
+		for _, u := range urls {
+			if u == "" {
+				return fmt.Errorf("failover url must not be empty")
+			}
+		}
+		c.failoverURLs = append(c.failoverURLs, urls...)
+		return nil
+	}
+}
+
+// WithLoadBalance spreads sends round-robin across the base URL and urls,
+// which must be equivalent Bark nodes. If a node is unreachable or responds
+// with a 5xx status, the send fails over to the remaining nodes and then to
+// any URLs set with WithFailoverURLs.
+func WithLoadBalance(urls []string) ClientOption {
+	return func(c *Client) error {
+		for _, u := range urls {
+			if u == "" {
+				return fmt.Errorf("load balance url must not be empty")
+			}
+		}
+		c.balancedURLs = append([]string{c.baseURL}, urls...)
+		return nil
+	}
+}
+
+// WithBodyTransform applies transform to every notification body before it is encoded,
+// for example to redact secrets or trim whitespace.
+// Multiple transforms are applied in the order they are given.
+func WithBodyTransform(transform func(string) string) ClientOption {
+	return func(c *Client) error {
+		if transform == nil {
+			return fmt.Errorf("body transform must not be nil")
+		}
+		c.bodyTransforms = append(c.bodyTransforms, transform)
+		return nil
+	}
+}
+
+// WithTitleTransform applies transform to every non-empty notification title
+// before it is encoded, for example to prefix an environment tag.
+// Multiple transforms are applied in the order they are given.
+func WithTitleTransform(transform func(string) string) ClientOption {
+	return func(c *Client) error {
+		if transform == nil {
+			return fmt.Errorf("title transform must not be nil")
+		}
+		c.titleTransforms = append(c.titleTransforms, transform)
+		return nil
+	}
+}
+
+// WithTitlePrefix adds prefix to the start of every non-empty notification title,
+// including the default title of notifications without WithTitle, for example
+// "[myapp] ". It is a title transform: it runs in order with those set by
+// WithTitleTransform and WithTitleSuffix.
+func WithTitlePrefix(prefix string) ClientOption {
+	return WithTitleTransform(func(title string) string {
+		return prefix + title
+	})
+}
+
+// WithTitleSuffix adds suffix to the end of every non-empty notification title,
+// including the default title of notifications without WithTitle. It is a title
+// transform: it runs in order with those set by WithTitleTransform and WithTitlePrefix.
+func WithTitleSuffix(suffix string) ClientOption {
+	return WithTitleTransform(func(title string) string {
+		return title + suffix
+	})
+}
+
+// WithSubtitleTransform applies transform to every non-empty notification subtitle
+// before it is encoded. Multiple transforms are applied in the order they are given.
+func WithSubtitleTransform(transform func(string) string) ClientOption {
+	return func(c *Client) error {
+		if transform == nil {
+			return fmt.Errorf("subtitle transform must not be nil")
+		}
+		c.subtitleTransforms = append(c.subtitleTransforms, transform)
+		return nil
+	}
+}
+
+// WithUnicodeNormalization normalizes the title, subtitle and body to form before
+// encoding, so that composed and decomposed characters render consistently.
+// The zero value of norm.Form is norm.NFC, the recommended form for display.
+func WithUnicodeNormalization(form norm.Form) ClientOption {
+	return func(c *Client) error {
+		switch form {
+		case norm.NFC, norm.NFD, norm.NFKC, norm.NFKD:
+		default:
+			return fmt.Errorf("unsupported unicode normalization form %v", form)
+		}
+		c.normalizeUnicode = true
+		c.unicodeForm = form
+		return nil
+	}
+}
+
+// WithBodyEncoder sets how the title, subtitle and body are escaped in the URL
+// path of GET requests, for servers that decode URLs differently.
+// The default is url.PathEscape, which encodes spaces as %20.
+func WithBodyEncoder(encode func(string) string) ClientOption {
+	return func(c *Client) error {
+		if encode == nil {
+			return fmt.Errorf("body encoder must not be nil")
+		}
+		c.encode = encode
+		return nil
+	}
+}
+
+// WithBase64Body base64-encodes every notification body (standard encoding with padding)
+// and sends the parameter encoding=base64 to signal it, so that bodies with
+// problematic bytes survive transport intact.
+// The stock Bark server and app do not decode the body: this requires a server
+// or client app that agrees to decode bodies sent with this parameter.
+func WithBase64Body() ClientOption {
+	return func(c *Client) error {
+		c.base64Body = true
+		return nil
+	}
+}
+
+// WithMaxBodyBytes limits the notification body to n bytes.
+// Longer bodies are cut on a rune boundary and end with "…". The first
+// character is always kept, even if it is longer than n bytes.
+func WithMaxBodyBytes(n int) ClientOption {
+	return func(c *Client) error {
+		if n <= 0 {
+			return fmt.Errorf("max body bytes must be positive")
+		}
+		c.maxBodyBytes = n
+		return nil
+	}
+}
+
+// defaultMaxURLLength is the URL length limit of WithMaxURLLength(0).
+const defaultMaxURLLength = 4096
+
+// ErrURLTooLong is returned by Send under WithMaxURLLength when the URL of a GET
+// request would exceed the limit.
+var ErrURLTooLong = errors.New("notification url too long")
+
+// WithMaxURLLength makes sends fail with ErrURLTooLong when the URL of a GET
+// request would be longer than n bytes, instead of risking servers or proxies
+// that silently truncate long URLs. Passing 0 uses a limit of 4096 bytes.
+// Use WithPostMode to send long notifications.
+func WithMaxURLLength(n int) ClientOption {
+	return func(c *Client) error {
+		if n < 0 {
+			return fmt.Errorf("max url length must not be negative")
+		}
+		if n == 0 {
+			n = defaultMaxURLLength
+		}
+		c.maxURLLength = n
+		return nil
+	}
+}
+
+// WithHostHeader sends requests with host as their Host header, while still
+// connecting to the address of the server URL. Use it to reach a server
+// through a proxy or load balancer that routes on the Host header.
+func WithHostHeader(host string) ClientOption {
+	return func(c *Client) error {
+		if host == "" {
+			return fmt.Errorf("host header must not be empty")
+		}
+		c.hostHeader = host
+		return nil
+	}
+}
+
+// WithMaxBodyLines limits the notification body to its first n lines, followed by
+// a "… (N more lines)" line counting the lines left out, so that long multi-line
+// bodies such as logs stay readable. It applies before WithMaxBodyBytes.
+func WithMaxBodyLines(n int) ClientOption {
+	return func(c *Client) error {
+		if n <= 0 {
+			return fmt.Errorf("max body lines must be positive")
+		}
+		c.maxBodyLines = n
+		return nil
+	}
+}
+
+// WithGroupRateLimit limits how often notifications in group are sent.
+// Sends exceeding the limit block until allowed or until the context is done;
+// each request of SendToKeys and SendBatch counts as one send.
+// Each group is throttled independently; notifications in other groups are unaffected.
+func WithGroupRateLimit(group string, limit rate.Limit) ClientOption {
+	return func(c *Client) error {
+		if group == "" {
+			return fmt.Errorf("rate limit group must not be empty")
+		}
+		if c.groupLimiters == nil {
+			c.groupLimiters = make(map[string]*rate.Limiter)
+		}
+		c.groupLimiters[group] = rate.NewLimiter(limit, 1)
+		return nil
+	}
+}
+
+// WithDedupWindow suppresses notifications whose title and body are identical
+// to one sent within the window d. Suppressed sends return ErrDuplicateSuppressed.
+// The client remembers a bounded number of recent notifications.
+func WithDedupWindow(d time.Duration) ClientOption {
+	return func(c *Client) error {
+		if d <= 0 {
+			return fmt.Errorf("dedup window must be positive")
+		}
+		c.dedup = newDedupCache(d)
+		return nil
+	}
+}
+
+// WithObserver sets an Observer that is notified at each phase of sending a notification.
+func WithObserver(o Observer) ClientOption {
+	return func(c *Client) error {
+		if o == nil {
+			return fmt.Errorf("observer must not be nil")
+		}
+		c.observer = o
+		return nil
+	}
+}
+
+// WithSlowSendThreshold calls cb with the elapsed time whenever sending a notification
+// takes longer than d, whether or not the send succeeds.
+func WithSlowSendThreshold(d time.Duration, cb func(duration time.Duration)) ClientOption {
+	return func(c *Client) error {
+		if d <= 0 {
+			return fmt.Errorf("slow send threshold must be positive")
+		}
+		if cb == nil {
+			return fmt.Errorf("slow send callback must not be nil")
+		}
+		c.slowSendThreshold = d
+		c.slowSendCallback = cb
+		return nil
+	}
+}
+
+// WithDefaultSubtitle sets the subtitle used when a notification has none.
+func WithDefaultSubtitle(subtitle string) ClientOption {
+	return func(c *Client) error {
+		c.defaultSubtitle = subtitle
+		return nil
+	}
+}
+
+// WithDefaultGroup sets the group used when a notification has none set with WithGroup,
+// so that all notifications from a service instance are grouped together on the device.
+func WithDefaultGroup(group string) ClientOption {
+	return func(c *Client) error {
+		c.defaultGroup = group
+		return nil
+	}
+}
+
+// WithDefaultSound sets the sound used when a notification has none set with WithSound,
+// including critical alerts, which otherwise play "alarm".
+// Passive notifications stay silent unless set with WithSound.
+func WithDefaultSound(sound string) ClientOption {
+	return func(c *Client) error {
+		c.defaultSound = sound
+		return nil
+	}
+}
+
+// WithDefaultBody sets the body used when Send is called with an empty body.
+func WithDefaultBody(body string) ClientOption {
+	return func(c *Client) error {
+		c.defaultBody = body
+		return nil
+	}
+}
+
+// WithHedging sends a second, concurrent request when the first has not completed
+// within delay, and uses whichever succeeds first, cancelling the other.
+// Hedging only applies to notifications with an ID set by WithID, since the server
+// deduplicates them by ID; observers see both requests.
+func WithHedging(delay time.Duration) ClientOption {
+	return func(c *Client) error {
+		if delay <= 0 {
+			return fmt.Errorf("hedging delay must be positive")
+		}
+		c.hedgeDelay = delay
+		return nil
+	}
+}
+
+// WithErrorResponseLogger calls logger with the status code and body of every
+// non-success response, to help diagnose rejected notifications.
+// The body is limited to the first 4 KiB; successful responses are not logged.
+func WithErrorResponseLogger(logger func(status int, body []byte)) ClientOption {
+	return func(c *Client) error {
+		if logger == nil {
+			return fmt.Errorf("error response logger must not be nil")
+		}
+		c.errorResponseLogger = logger
+		return nil
+	}
+}
+
+// WithTreatCancelAsSuccess makes Send return nil instead of an error when the
+// send is cancelled through its context, as expected for best-effort notifications
+// sent during shutdown. Cancelled sends are not passed to the dead letter handler.
+// Deadlines exceeded are still reported as errors.
+func WithTreatCancelAsSuccess() ClientOption {
+	return func(c *Client) error {
+		c.cancelAsSuccess = true
+		return nil
+	}
+}
+
+// DeadLetterFunc receives a notification that could not be delivered, along with
+// the final error, so that it can be persisted and replayed later.
+type DeadLetterFunc func(ctx context.Context, body string, opts []Option, err error)
+
+// WithDeadLetter sets a handler that is called when a send ultimately fails,
+// after any retries and failover servers have been exhausted.
+// It is not called for notifications rejected before sending, such as invalid
+// or duplicate notifications.
+func WithDeadLetter(handler DeadLetterFunc) ClientOption {
+	return func(c *Client) error {
+		if handler == nil {
+			return fmt.Errorf("dead letter handler must not be nil")
+		}
+		c.deadLetter = handler
+		return nil
+	}
+}
+
+// WithResponseHeaderCallback calls cb with the headers of every response received,
+// successful or not, for example to read quota or server version headers.
+func WithResponseHeaderCallback(cb func(http.Header)) ClientOption {
+	return func(c *Client) error {
+		if cb == nil {
+			return fmt.Errorf("response header callback must not be nil")
+		}
+		c.responseHeaderCallback = cb
+		return nil
+	}
+}
+
+// WithSendInterceptor calls interceptor with every request just before it is sent,
+// after it has been fully built. The interceptor may modify the request, for example
+// to add headers or scrub its content, or abort the send by returning an error,
+// which is returned by Send wrapped. Aborted sends are neither retried nor failed over.
+func WithSendInterceptor(interceptor func(ctx context.Context, req *http.Request) error) ClientOption {
+	return func(c *Client) error {
+		if interceptor == nil {
+			return fmt.Errorf("send interceptor must not be nil")
+		}
+		c.sendInterceptor = interceptor
+		return nil
+	}
+}
+
+// WithArchiveGroups sets whether the server archives notifications by group:
+// notifications in a group mapped to true are saved in the app's history and
+// those mapped to false are not. Notifications in other groups, or without a
+// group, use the server default.
+func WithArchiveGroups(groups map[string]bool) ClientOption {
+	return func(c *Client) error {
+		archiveGroups := make(map[string]bool, len(groups))
+		for group, archive := range groups {
+			archiveGroups[group] = archive
+		}
+		c.archiveGroups = archiveGroups
+		return nil
+	}
+}
+
+// WithLevelVolumes sets the volume of notifications by level, for notifications
+// whose volume is not set with WithVolume. Volumes range from 0 to 10.
+// The Bark app only applies the volume to critical alerts, so volumes set for
+// other levels only take effect on servers and apps that support them.
+func WithLevelVolumes(volumes map[NotificationLevel]int) ClientOption {
+	return func(c *Client) error {
+		levelVolumes := make(map[NotificationLevel]int, len(volumes))
+		for level, volume := range volumes {
+			if volume < 0 || volume > maxVolume {
+				return fmt.Errorf("volume for level %q must be between 0 and %d, got %d", level, maxVolume, volume)
+			}
+			levelVolumes[level] = volume
+		}
+		c.levelVolumes = levelVolumes
+		return nil
+	}
+}
@@ -0,0 +1,1486 @@
+package gobark
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xpzouying/gobark/barktest"
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/time/rate"
+)
+
+// newRecordingClient returns a client that records its requests with a barktest.RecordingTransport.
+func newRecordingClient(t *testing.T, opts ...ClientOption) (*Client, *barktest.RecordingTransport) {
+	t.Helper()
+
+	transport := &barktest.RecordingTransport{}
+	opts = append([]ClientOption{WithHTTPClient(&http.Client{Transport: transport})}, opts...)
+	client, err := NewClient("https://bark.example.com", "test-key", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client, transport
+}
+
+// newCaptureClient starts a test server that records every request it receives
+// and returns a client pointing at it, along with a function returning the last request.
+func newCaptureClient(t *testing.T, opts ...ClientOption) (*Client, func() *http.Request) {
+	t.Helper()
+
+	var (
+		mu   sync.Mutex
+		last *http.Request
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		last = r
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(server.URL, "test-key", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client, func() *http.Request {
+		mu.Lock()
+		defer mu.Unlock()
+		return last
+	}
+}
+
+func TestWithFailoverURLs(t *testing.T) {
+	// The primary server is down: start it and close it immediately
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	primary.Close()
+
+	var backupHits int
+	backup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		backupHits++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backup.Close()
+
+	client, err := NewClient(primary.URL, "test-key", WithFailoverURLs(backup.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Send(context.Background(), "test message"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if backupHits != 1 {
+		t.Errorf("backup hits = %d, want 1", backupHits)
+	}
+}
+
+func TestWithFailoverURLs_NoFailoverOnClientError(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer primary.Close()
+
+	var backupHits int
+	backup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		backupHits++
+	}))
+	defer backup.Close()
+
+	client, err := NewClient(primary.URL, "test-key", WithFailoverURLs(backup.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Send(context.Background(), "test message"); err == nil {
+		t.Fatal("Send() error = nil, want error")
+	}
+	if backupHits != 0 {
+		t.Errorf("backup hits = %d, want 0", backupHits)
+	}
+}
+
+func TestWithFailoverURLs_AllFail(t *testing.T) {
+	newFailing := func() *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+	}
+	primary, backup := newFailing(), newFailing()
+	defer primary.Close()
+	defer backup.Close()
+
+	client, err := NewClient(primary.URL, "test-key", WithFailoverURLs(backup.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = client.Send(context.Background(), "test message")
+	if err == nil || err.Error() != "unexpected status code: 502" {
+		t.Errorf("Send() error = %v, want last status error", err)
+	}
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	var hits int
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer server.Close()
+
+	// Only the server's own client trusts its certificate
+	client, err := NewClient(server.URL, "test-key", WithHTTPClient(server.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Send(context.Background(), "test message"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if hits != 1 {
+		t.Errorf("server hits = %d, want 1", hits)
+	}
+}
+
+func TestWithHTTPClient_Nil(t *testing.T) {
+	if _, err := NewClient("", "test-key", WithHTTPClient(nil)); err == nil {
+		t.Error("NewClient() error = nil, want error")
+	}
+}
+
+func TestWithPostMode(t *testing.T) {
+	var (
+		method, path, contentType string
+		payload                   map[string]string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path, contentType = r.Method, r.URL.Path, r.Header.Get("Content-Type")
+		json.NewDecoder(r.Body).Decode(&payload)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "test-key", WithPostMode())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Send(context.Background(), "Line 1\nLine 2", WithTitle("Deploy"), WithURL("https://example.com")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if method != http.MethodPost || path != "/push" {
+		t.Errorf("request = %s %s, want POST /push", method, path)
+	}
+	if !strings.HasPrefix(contentType, "application/json") {
+		t.Errorf("Content-Type = %q, want JSON", contentType)
+	}
+	want := map[string]string{"device_key": "test-key", "title": "Deploy", "body": "Line 1\nLine 2", "url": "https://example.com"}
+	if !reflect.DeepEqual(payload, want) {
+		t.Errorf("payload = %v, want %v", payload, want)
+	}
+}
+
+func TestSendError(t *testing.T) {
+	client, lastRequest := newCaptureClient(t)
+
+	sample := &strconv.NumError{Func: "ParseInt", Num: "abc", Err: strconv.ErrSyntax}
+	if err := client.SendError(context.Background(), sample); err != nil {
+		t.Fatalf("SendError() error = %v", err)
+	}
+
+	req := lastRequest()
+	wantPath := "/test-key/" + defaultTitle + "/NumError/" + sample.Error()
+	if req.URL.Path != wantPath {
+		t.Errorf("path = %q, want %q", req.URL.Path, wantPath)
+	}
+	if got := req.URL.Query().Get("level"); got != string(LevelTimeSensitive) {
+		t.Errorf("level = %q, want %q", got, LevelTimeSensitive)
+	}
+}
+
+func TestSendError_NilError(t *testing.T) {
+	client, _ := newCaptureClient(t)
+
+	if err := client.SendError(context.Background(), nil); err == nil {
+		t.Error("SendError(nil) error = nil, want error")
+	}
+}
+
+func TestWithMaxBodyBytes(t *testing.T) {
+	client, lastRequest := newCaptureClient(t, WithMaxBodyBytes(10))
+
+	// Each character is 3 bytes, so 10 bytes leave room for two characters plus the marker
+	if err := client.Send(context.Background(), "你好世界你好世界", WithTitle("Truncate")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	want := "/test-key/Truncate/你好…"
+	if got := lastRequest().URL.Path; got != want {
+		t.Errorf("path = %q, want %q", got, want)
+	}
+}
+
+func TestTruncateBody(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		maxBytes int
+		want     string
+	}{
+		{name: "short body", body: "hello", maxBytes: 10, want: "hello"},
+		{name: "ascii body", body: "hello world", maxBytes: 8, want: "hello…"},
+		{name: "multi-byte body", body: "héllo wörld", maxBytes: 6, want: "hé…"},
+		{name: "no room for marker", body: "你好世界", maxBytes: 3, want: "你"},
+		{name: "no room for a rune", body: "日本語", maxBytes: 2, want: "日"},
+		{name: "no room for a rune and marker", body: "日本語", maxBytes: 5, want: "日"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateBody(tt.body, tt.maxBytes)
+			if got != tt.want {
+				t.Errorf("truncateBody() = %q, want %q", got, tt.want)
+			}
+			// Only a lone first rune may exceed the limit
+			if len(got) > tt.maxBytes && utf8.RuneCountInString(got) > 1 {
+				t.Errorf("truncateBody() length = %d, want <= %d", len(got), tt.maxBytes)
+			}
+		})
+	}
+}
+
+func TestWithMaxBodyLines(t *testing.T) {
+	client, transport := newRecordingClient(t, WithMaxBodyLines(3))
+
+	lines := make([]string, 10)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i+1)
+	}
+	if err := client.Send(context.Background(), strings.Join(lines, "\n")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	want := "line 1\nline 2\nline 3\n… (7 more lines)"
+	if got := transport.Requests()[0].Body; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+func TestTruncateLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		maxLines int
+		want     string
+	}{
+		{name: "single line", body: "hello", maxLines: 1, want: "hello"},
+		{name: "within limit", body: "a\nb", maxLines: 2, want: "a\nb"},
+		{name: "trailing newline", body: "a\nb\n", maxLines: 2, want: "a\nb\n"},
+		{name: "one line over", body: "a\nb\nc", maxLines: 2, want: "a\nb\n… (1 more lines)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncateLines(tt.body, tt.maxLines); got != tt.want {
+				t.Errorf("truncateLines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithCopyURL(t *testing.T) {
+	client, lastRequest := newCaptureClient(t)
+
+	link := "https://example.com/build/42?tab=logs"
+	if err := client.Send(context.Background(), "Build finished", WithURL(link), WithCopyURL()); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	query := lastRequest().URL.Query()
+	if got := query.Get("url"); got != link {
+		t.Errorf("url = %q, want %q", got, link)
+	}
+	if got := query.Get("copy"); got != query.Get("url") {
+		t.Errorf("copy = %q, want %q", got, query.Get("url"))
+	}
+}
+
+func TestWithCopyURL_RequiresURL(t *testing.T) {
+	client, lastRequest := newCaptureClient(t)
+
+	if err := client.Send(context.Background(), "Build finished", WithCopyURL()); err == nil {
+		t.Fatal("Send() error = nil, want error")
+	}
+	if lastRequest() != nil {
+		t.Error("request was sent, want none")
+	}
+}
+
+func TestWithGroupRateLimit(t *testing.T) {
+	client, _ := newCaptureClient(t, WithGroupRateLimit("backup", rate.Every(time.Minute)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if err := client.Send(ctx, "backup started", WithGroup("backup")); err != nil {
+		t.Fatalf("first Send() error = %v", err)
+	}
+	if err := client.Send(ctx, "backup finished", WithGroup("backup")); err == nil {
+		t.Error("second Send() in throttled group error = nil, want rate limit error")
+	}
+
+	// Other groups and ungrouped notifications flow freely
+	for i := 0; i < 3; i++ {
+		if err := client.Send(ctx, "deploy", WithGroup("deploy")); err != nil {
+			t.Errorf("Send() in other group error = %v", err)
+		}
+		if err := client.Send(ctx, "ungrouped"); err != nil {
+			t.Errorf("Send() without group error = %v", err)
+		}
+	}
+}
+
+func TestSendJSON(t *testing.T) {
+	client, transport := newRecordingClient(t)
+
+	status := struct {
+		Host string `json:"host"`
+		CPU  int    `json:"cpu"`
+	}{Host: "db01", CPU: 92}
+	if err := client.SendJSON(context.Background(), status, WithTitle("Status")); err != nil {
+		t.Fatalf("SendJSON() error = %v", err)
+	}
+
+	want := "{\n  \"host\": \"db01\",\n  \"cpu\": 92\n}"
+	if got := transport.Requests()[0].Body; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+func TestSendJSON_MarshalError(t *testing.T) {
+	client, transport := newRecordingClient(t)
+
+	if err := client.SendJSON(context.Background(), make(chan int)); err == nil {
+		t.Error("SendJSON() error = nil, want marshal error")
+	}
+	if len(transport.Requests()) != 0 {
+		t.Error("request was sent, want none")
+	}
+}
+
+func TestWithCriticalNotify_DefaultSound(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []Option
+		wantSound string
+	}{
+		{
+			name:      "default critical sound",
+			opts:      []Option{WithCriticalNotify()},
+			wantSound: defaultCriticalSound,
+		},
+		{
+			name:      "explicit sound after critical",
+			opts:      []Option{WithCriticalNotify(), WithSound("bell")},
+			wantSound: "bell",
+		},
+		{
+			name:      "explicit sound before critical",
+			opts:      []Option{WithSound("bell"), WithCriticalNotify()},
+			wantSound: "bell",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, transport := newRecordingClient(t)
+
+			if err := client.Send(context.Background(), "server down", tt.opts...); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			if got := transport.Requests()[0].Params["sound"]; got != tt.wantSound {
+				t.Errorf("sound = %q, want %q", got, tt.wantSound)
+			}
+		})
+	}
+}
+
+func TestWithAutoGzip(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		wantEncoding string
+	}{
+		{name: "above threshold", body: strings.Repeat("log line\n", 100), wantEncoding: "gzip"},
+		{name: "below threshold", body: "short", wantEncoding: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, transport := newRecordingClient(t, WithPostMode(), WithAutoGzip(256))
+
+			if err := client.Send(context.Background(), tt.body); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			req := transport.Requests()[0]
+			if got := req.Header.Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Errorf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			if req.Body != tt.body {
+				t.Errorf("body = %q, want %q", req.Body, tt.body)
+			}
+		})
+	}
+}
+
+func TestWithFormEncoded(t *testing.T) {
+	var (
+		contentType string
+		form        url.Values
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		if r.URL.Path != "/push" || r.Method != http.MethodPost {
+			t.Errorf("request = %s %s, want POST /push", r.Method, r.URL.Path)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("ParseForm() error = %v", err)
+		}
+		form = r.PostForm
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "test-key", WithFormEncoded())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Send(context.Background(), "Line 1\nLine 2 & more", WithTitle("Deploy"), WithSound("bell")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if contentType != "application/x-www-form-urlencoded" {
+		t.Errorf("Content-Type = %q, want %q", contentType, "application/x-www-form-urlencoded")
+	}
+	want := url.Values{
+		"device_key": {"test-key"},
+		"title":      {"Deploy"},
+		"body":       {"Line 1\nLine 2 & more"},
+		"sound":      {"bell"},
+	}
+	if !reflect.DeepEqual(form, want) {
+		t.Errorf("form = %v, want %v", form, want)
+	}
+}
+
+func TestWithSlowSendThreshold(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	threshold := 10 * time.Millisecond
+	var reported []time.Duration
+	client, err := NewClient(server.URL, "test-key", WithSlowSendThreshold(threshold, func(d time.Duration) {
+		reported = append(reported, d)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Send(context.Background(), "test message"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if len(reported) != 1 {
+		t.Fatalf("callback fired %d times, want 1", len(reported))
+	}
+	if reported[0] <= threshold {
+		t.Errorf("reported duration = %v, want > %v", reported[0], threshold)
+	}
+}
+
+func TestWithSlowSendThreshold_FastSend(t *testing.T) {
+	var fired bool
+	client, _ := newRecordingClient(t, WithSlowSendThreshold(time.Second, func(time.Duration) {
+		fired = true
+	}))
+
+	if err := client.Send(context.Background(), "test message"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if fired {
+		t.Error("callback fired for a fast send")
+	}
+}
+
+func TestWithDefaultSubtitleAndBody(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		opts         []Option
+		wantSubtitle string
+		wantBody     string
+	}{
+		{
+			name:         "defaults apply when unset",
+			wantSubtitle: "myapp",
+			wantBody:     "no details",
+		},
+		{
+			name:         "explicit values override defaults",
+			body:         "disk full",
+			opts:         []Option{WithSubtitle("db01")},
+			wantSubtitle: "db01",
+			wantBody:     "disk full",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, transport := newRecordingClient(t, WithDefaultSubtitle("myapp"), WithDefaultBody("no details"))
+
+			if err := client.Send(context.Background(), tt.body, tt.opts...); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			req := transport.Requests()[0]
+			if req.Subtitle != tt.wantSubtitle {
+				t.Errorf("subtitle = %q, want %q", req.Subtitle, tt.wantSubtitle)
+			}
+			if req.Body != tt.wantBody {
+				t.Errorf("body = %q, want %q", req.Body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestWithDefaultGroup(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []Option
+		wantGroup string
+	}{
+		{name: "default applies when unset", wantGroup: "api-01"},
+		{name: "explicit group overrides default", opts: []Option{WithGroup("deploys")}, wantGroup: "deploys"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, transport := newRecordingClient(t, WithDefaultGroup("api-01"))
+
+			if err := client.Send(context.Background(), "disk full", tt.opts...); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			if got := transport.Requests()[0].Params["group"]; got != tt.wantGroup {
+				t.Errorf("group = %q, want %q", got, tt.wantGroup)
+			}
+		})
+	}
+}
+
+func TestWithDefaultSound(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []Option
+		wantSound string
+	}{
+		{name: "default sound", opts: nil, wantSound: "bell"},
+		{name: "explicit sound", opts: []Option{WithSound("minuet")}, wantSound: "minuet"},
+		{name: "critical", opts: []Option{WithCriticalNotify()}, wantSound: "bell"},
+		{name: "passive stays silent", opts: []Option{WithPassive()}, wantSound: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, transport := newRecordingClient(t, WithDefaultSound("bell"))
+
+			if err := client.Send(context.Background(), "test message", tt.opts...); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			if got := transport.Requests()[0].Params["sound"]; got != tt.wantSound {
+				t.Errorf("sound = %q, want %q", got, tt.wantSound)
+			}
+		})
+	}
+}
+
+func TestSend_EmptyBodyWithoutDefault(t *testing.T) {
+	client, _ := newRecordingClient(t)
+
+	if err := client.Send(context.Background(), ""); err == nil {
+		t.Error("Send() error = nil, want body required error")
+	}
+}
+
+func TestWithDeviceField(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []ClientOption
+		wantField string
+		otherKey  string
+	}{
+		{name: "default", wantField: "device_key", otherKey: "device_token"},
+		{name: "device key", opts: []ClientOption{WithDeviceField(DeviceKeyField)}, wantField: "device_key", otherKey: "device_token"},
+		{name: "device token", opts: []ClientOption{WithDeviceField(DeviceTokenField)}, wantField: "device_token", otherKey: "device_key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var payload map[string]string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+					t.Errorf("decode payload: %v", err)
+				}
+			}))
+			defer server.Close()
+
+			client, err := NewClient(server.URL, "test-key", append([]ClientOption{WithPostMode()}, tt.opts...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := client.Send(context.Background(), "test message"); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			if got := payload[tt.wantField]; got != "test-key" {
+				t.Errorf("payload[%q] = %q, want %q", tt.wantField, got, "test-key")
+			}
+			if _, ok := payload[tt.otherKey]; ok {
+				t.Errorf("payload contains %q, want only %q", tt.otherKey, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestWithDeviceField_Invalid(t *testing.T) {
+	if _, err := NewClient("", "test-key", WithDeviceField("device_id")); err == nil {
+		t.Error("NewClient() error = nil, want error")
+	}
+}
+
+func TestWithLoadBalance(t *testing.T) {
+	const nodes, sends = 3, 30
+
+	hits := make([]int, nodes)
+	urls := make([]string, nodes)
+	for i := range urls {
+		i := i
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits[i]++
+		}))
+		defer server.Close()
+		urls[i] = server.URL
+	}
+
+	client, err := NewClient(urls[0], "test-key", WithLoadBalance(urls[1:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < sends; i++ {
+		if err := client.Send(context.Background(), "test message"); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	for i, got := range hits {
+		if got != sends/nodes {
+			t.Errorf("node %d hits = %d, want %d", i, got, sends/nodes)
+		}
+	}
+}
+
+func TestWithLoadBalance_FailsOverToHealthyNode(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	down.Close()
+
+	var healthyHits int
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		healthyHits++
+	}))
+	defer healthy.Close()
+
+	client, err := NewClient(down.URL, "test-key", WithLoadBalance([]string{healthy.URL}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 4; i++ {
+		if err := client.Send(context.Background(), "test message"); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+	if healthyHits != 4 {
+		t.Errorf("healthy node hits = %d, want 4", healthyHits)
+	}
+}
+
+func TestWithBodyTransform(t *testing.T) {
+	token := regexp.MustCompile(`token=\S+`)
+	client, transport := newRecordingClient(t,
+		WithBodyTransform(strings.TrimSpace),
+		WithBodyTransform(func(body string) string {
+			return token.ReplaceAllString(body, "token=***")
+		}),
+	)
+
+	if err := client.Send(context.Background(), "  login failed for token=abc123 from 10.0.0.1\n"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	want := "login failed for token=*** from 10.0.0.1"
+	if got := transport.Requests()[0].Body; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+func TestWithTitleAndSubtitleTransform(t *testing.T) {
+	prefix := func(tag string) func(string) string {
+		return func(s string) string { return tag + " " + s }
+	}
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		wantPath string
+	}{
+		{
+			name:     "title and subtitle",
+			opts:     []Option{WithTitle("Deploy"), WithSubtitle("api")},
+			wantPath: "/test-key/[PROD] Deploy/(eu) api/done",
+		},
+		{
+			name:     "empty subtitle is left empty",
+			opts:     []Option{WithTitle("Deploy")},
+			wantPath: "/test-key/[PROD] Deploy/done",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, lastRequest := newCaptureClient(t,
+				WithTitleTransform(prefix("[PROD]")),
+				WithSubtitleTransform(prefix("(eu)")),
+			)
+
+			if err := client.Send(context.Background(), "done", tt.opts...); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			if got := lastRequest().URL.Path; got != tt.wantPath {
+				t.Errorf("path = %q, want %q", got, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestWithTitlePrefixAndSuffix(t *testing.T) {
+	tests := []struct {
+		name       string
+		clientOpts []ClientOption
+		opts       []Option
+		wantPath   string
+	}{
+		{
+			name:       "prefix",
+			clientOpts: []ClientOption{WithTitlePrefix("[myapp] ")},
+			opts:       []Option{WithTitle("Deploy")},
+			wantPath:   "/test-key/[myapp] Deploy/done",
+		},
+		{
+			name:       "suffix",
+			clientOpts: []ClientOption{WithTitleSuffix(" (prod)")},
+			opts:       []Option{WithTitle("Deploy")},
+			wantPath:   "/test-key/Deploy (prod)/done",
+		},
+		{
+			name:       "default title",
+			clientOpts: []ClientOption{WithTitlePrefix("[myapp] ")},
+			wantPath:   "/test-key/[myapp] " + defaultTitle + "/done",
+		},
+		{
+			name:       "empty title is left empty",
+			clientOpts: []ClientOption{WithTitlePrefix("[myapp] ")},
+			opts:       []Option{WithTitle("")},
+			wantPath:   "/test-key/done",
+		},
+		{
+			name:       "composed with transforms in order",
+			clientOpts: []ClientOption{WithTitlePrefix("[myapp] "), WithTitleTransform(strings.ToUpper), WithTitleSuffix("!")},
+			opts:       []Option{WithTitle("Deploy")},
+			wantPath:   "/test-key/[MYAPP] DEPLOY!/done",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, lastRequest := newCaptureClient(t, tt.clientOpts...)
+
+			if err := client.Send(context.Background(), "done", tt.opts...); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			if got := lastRequest().URL.Path; got != tt.wantPath {
+				t.Errorf("path = %q, want %q", got, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestWithPassive_Sound(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []Option
+		wantSound string
+	}{
+		{name: "no sound by default", opts: []Option{WithPassive()}, wantSound: ""},
+		{name: "passive overrides critical", opts: []Option{WithCriticalNotify(), WithPassive()}, wantSound: ""},
+		{name: "explicit sound", opts: []Option{WithPassive(), WithSound("bell")}, wantSound: "bell"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, transport := newRecordingClient(t)
+
+			if err := client.Send(context.Background(), "nightly report ready", tt.opts...); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			req := transport.Requests()[0]
+			if got := req.Params["level"]; got != string(LevelPassive) {
+				t.Errorf("level = %q, want %q", got, LevelPassive)
+			}
+			if got, ok := req.Params["sound"]; got != tt.wantSound || (tt.wantSound == "" && ok) {
+				t.Errorf("sound = %q (present %v), want %q", got, ok, tt.wantSound)
+			}
+		})
+	}
+}
+
+func TestWithTimeSensitiveTTL(t *testing.T) {
+	tests := []struct {
+		name           string
+		ttl            time.Duration
+		wantExpiration string
+	}{
+		{name: "whole seconds", ttl: 5 * time.Minute, wantExpiration: "300"},
+		{name: "fractional seconds", ttl: 1500 * time.Millisecond, wantExpiration: "2"},
+		{name: "under a second", ttl: 100 * time.Millisecond, wantExpiration: "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, transport := newRecordingClient(t)
+
+			if err := client.Send(context.Background(), "your code is 123456", WithTimeSensitiveTTL(tt.ttl)); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			req := transport.Requests()[0]
+			if got := req.Params["level"]; got != string(LevelTimeSensitive) {
+				t.Errorf("level = %q, want %q", got, LevelTimeSensitive)
+			}
+			if got := req.Params["expiration"]; got != tt.wantExpiration {
+				t.Errorf("expiration = %q, want %q", got, tt.wantExpiration)
+			}
+		})
+	}
+}
+
+func TestWithHaptic(t *testing.T) {
+	client, lastRequest := newCaptureClient(t)
+
+	if err := client.Send(context.Background(), "build finished", WithHaptic("double")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	query := lastRequest().URL.Query()
+	if got := query.Get("haptic"); got != "double" {
+		t.Errorf("haptic = %q, want %q", got, "double")
+	}
+	if query.Has("sound") {
+		t.Errorf("sound = %q, want no sound", query.Get("sound"))
+	}
+}
+
+func TestWithArchiveGroups(t *testing.T) {
+	tests := []struct {
+		name        string
+		group       string
+		wantArchive string
+	}{
+		{name: "archived group", group: "deploys", wantArchive: "1"},
+		{name: "unarchived group", group: "heartbeats", wantArchive: "0"},
+		{name: "unmapped group", group: "alerts", wantArchive: ""},
+		{name: "no group", wantArchive: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, lastRequest := newCaptureClient(t, WithArchiveGroups(map[string]bool{"deploys": true, "heartbeats": false}))
+
+			if err := client.Send(context.Background(), "test message", WithGroup(tt.group)); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			query := lastRequest().URL.Query()
+			if got := query.Get("isArchive"); got != tt.wantArchive {
+				t.Errorf("isArchive = %q, want %q", got, tt.wantArchive)
+			}
+			if tt.wantArchive == "" && query.Has("isArchive") {
+				t.Errorf("isArchive set, want the server default")
+			}
+		})
+	}
+}
+
+func TestWithArchiveGroups_CopiesMap(t *testing.T) {
+	groups := map[string]bool{"deploys": true}
+	client, lastRequest := newCaptureClient(t, WithArchiveGroups(groups))
+
+	// Changing the map after NewClient does not affect the client
+	groups["deploys"] = false
+
+	if err := client.Send(context.Background(), "test message", WithGroup("deploys")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if got := lastRequest().URL.Query().Get("isArchive"); got != "1" {
+		t.Errorf("isArchive = %q, want %q", got, "1")
+	}
+}
+
+func TestWithSoundRepeat(t *testing.T) {
+	client, lastRequest := newCaptureClient(t)
+
+	if err := client.Send(context.Background(), "server down", WithSound("alarm"), WithSoundRepeat(3)); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	query := lastRequest().URL.Query()
+	if got := query.Get("repeat"); got != "3" {
+		t.Errorf("repeat = %q, want %q", got, "3")
+	}
+	if got := query.Get("call"); got != "1" {
+		t.Errorf("call = %q, want %q as the fallback", got, "1")
+	}
+}
+
+func TestWithImageData(t *testing.T) {
+	client, transport := newRecordingClient(t)
+
+	png := []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+	if err := client.Send(context.Background(), "chart attached", WithImageData(png, "image/png")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	req := transport.Requests()[0]
+	if req.Method != http.MethodPost {
+		t.Errorf("method = %s, want POST for image data", req.Method)
+	}
+	if want := "data:image/png;base64,iVBORw0KGgo="; req.Params["image"] != want {
+		t.Errorf("image = %q, want %q", req.Params["image"], want)
+	}
+	if req.Body != "chart attached" {
+		t.Errorf("body = %q, want %q", req.Body, "chart attached")
+	}
+}
+
+func TestWithContentAvailable(t *testing.T) {
+	client, transport := newRecordingClient(t)
+
+	if err := client.Send(context.Background(), "sync", WithContentAvailable(), WithPassive()); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	req := transport.Requests()[0]
+	if got := req.Params["contentAvailable"]; got != "1" {
+		t.Errorf("contentAvailable = %q, want %q", got, "1")
+	}
+	if got := req.Params["level"]; got != string(LevelPassive) {
+		t.Errorf("level = %q, want %q", got, LevelPassive)
+	}
+}
+
+func TestWithCollapseID(t *testing.T) {
+	client, lastRequest := newCaptureClient(t)
+
+	if err := client.Send(context.Background(), "score 2-1", WithCollapseID("match-42"), WithID("score")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	query := lastRequest().URL.Query()
+	if got := query.Get("collapseId"); got != "match-42" {
+		t.Errorf("collapseId = %q, want %q", got, "match-42")
+	}
+	if got := query.Get("id"); got != "score" {
+		t.Errorf("id = %q, want %q", got, "score")
+	}
+}
+
+func TestBuildNotificationURL_SubpathBaseURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseURL string
+	}{
+		{name: "without trailing slash", baseURL: "https://host/notify"},
+		{name: "with trailing slash", baseURL: "https://host/notify/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient(tt.baseURL, "test-key")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			n := NewNotification("hello world", WithTitle(""))
+			want := "https://host/notify/test-key/hello%20world"
+			if got := client.buildNotificationURL(tt.baseURL, client.key, n); got != want {
+				t.Errorf("buildNotificationURL() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestSend_SubpathBaseURL(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+	}))
+	defer server.Close()
+
+	for _, opts := range [][]ClientOption{nil, {WithPostMode()}} {
+		client, err := NewClient(server.URL+"/notify/", "test-key", opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := client.Send(context.Background(), "hello", WithTitle("")); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	want := []string{"/notify/test-key/hello", "/notify/push"}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Errorf("paths = %v, want %v", paths, want)
+	}
+}
+
+func TestSendVerbose(t *testing.T) {
+	client, _ := newRecordingClient(t, WithBodyTransform(strings.ToUpper))
+
+	opts := []Option{WithTitle("Deploy"), WithSound("bell")}
+	want, err := client.BuildURL(context.Background(), "line 1\nline 2", opts...)
+	if err != nil {
+		t.Fatalf("BuildURL() error = %v", err)
+	}
+
+	got, err := client.SendVerbose(context.Background(), "line 1\nline 2", opts...)
+	if err != nil {
+		t.Fatalf("SendVerbose() error = %v", err)
+	}
+
+	if got != want {
+		t.Errorf("SendVerbose() url = %q, want BuildURL() %q", got, want)
+	}
+	if wantURL := "https://bark.example.com/test-key/Deploy/LINE%201%0ALINE%202?sound=bell"; got != wantURL {
+		t.Errorf("SendVerbose() url = %q, want %q", got, wantURL)
+	}
+}
+
+func TestSendStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		wantStatus int
+		wantErr    bool
+	}{
+		{name: "success", status: http.StatusOK, wantStatus: http.StatusOK},
+		{name: "client error", status: http.StatusBadRequest, wantStatus: http.StatusBadRequest, wantErr: true},
+		{name: "server error", status: http.StatusInternalServerError, wantStatus: http.StatusInternalServerError, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &barktest.RecordingTransport{StatusCode: tt.status}
+			client, err := NewClient("", "test-key", WithHTTPClient(&http.Client{Transport: transport}))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			status, err := client.SendStatus(context.Background(), "test message")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SendStatus() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if status != tt.wantStatus {
+				t.Errorf("SendStatus() status = %d, want %d", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestWithUnicodeNormalization(t *testing.T) {
+	client, lastRequest := newCaptureClient(t, WithUnicodeNormalization(norm.NFC))
+
+	// "Cafe\u0301" spells Café with a combining acute accent
+	if err := client.Send(context.Background(), "Cafe\u0301 open", WithTitle("Cafe\u0301")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	want := "/test-key/Caf%C3%A9/Caf%C3%A9%20open"
+	if got := lastRequest().URL.EscapedPath(); got != want {
+		t.Errorf("path = %q, want %q", got, want)
+	}
+}
+
+func TestWithUnicodeNormalization_Disabled(t *testing.T) {
+	client, lastRequest := newCaptureClient(t)
+
+	if err := client.Send(context.Background(), "Cafe\u0301"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if got := lastRequest().URL.EscapedPath(); !strings.HasSuffix(got, "/Cafe%CC%81") {
+		t.Errorf("path = %q, want the decomposed body unchanged", got)
+	}
+}
+
+func TestWithErrorResponseLogger(t *testing.T) {
+	var (
+		loggedStatus int
+		loggedBody   string
+		calls        int
+	)
+	logger := func(status int, body []byte) {
+		calls++
+		loggedStatus, loggedBody = status, string(body)
+	}
+
+	errorBody := `{"code":400,"message":"failed to get device token: invalid key"}`
+	transport := &barktest.RecordingTransport{StatusCode: http.StatusBadRequest, ResponseBody: errorBody}
+	client, err := NewClient("", "test-key",
+		WithHTTPClient(&http.Client{Transport: transport}),
+		WithErrorResponseLogger(logger),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Send(context.Background(), "test message"); err == nil {
+		t.Fatal("Send() error = nil, want error")
+	}
+
+	if calls != 1 {
+		t.Fatalf("logger calls = %d, want 1", calls)
+	}
+	if loggedStatus != http.StatusBadRequest {
+		t.Errorf("logged status = %d, want %d", loggedStatus, http.StatusBadRequest)
+	}
+	if loggedBody != errorBody {
+		t.Errorf("logged body = %q, want %q", loggedBody, errorBody)
+	}
+
+	// Successful responses are not logged
+	transport.StatusCode = http.StatusOK
+	if err := client.Send(context.Background(), "test message"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("logger calls = %d after success, want 1", calls)
+	}
+}
+
+func TestWithBodyEncoder(t *testing.T) {
+	client, lastRequest := newCaptureClient(t, WithBodyEncoder(url.QueryEscape))
+
+	if err := client.Send(context.Background(), "disk almost full", WithTitle("db 01"), WithSubtitle("eu west")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	want := "/test-key/db+01/eu+west/disk+almost+full"
+	if got := lastRequest().URL.EscapedPath(); got != want {
+		t.Errorf("path = %q, want %q", got, want)
+	}
+}
+
+func TestWithBase64Body(t *testing.T) {
+	client, transport := newRecordingClient(t, WithBase64Body())
+
+	body := "tab\tnull\x00 emoji 🚀"
+	if err := client.Send(context.Background(), body); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	req := transport.Requests()[0]
+	if want := base64.StdEncoding.EncodeToString([]byte(body)); req.Body != want {
+		t.Errorf("body = %q, want %q", req.Body, want)
+	}
+	if got := req.Params["encoding"]; got != "base64" {
+		t.Errorf("encoding = %q, want %q", got, "base64")
+	}
+}
+
+func TestWithResponseHeaderCallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Quota-Remaining", "41")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var headers []http.Header
+	client, err := NewClient(server.URL, "test-key", WithResponseHeaderCallback(func(h http.Header) {
+		headers = append(headers, h)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Send(context.Background(), "test message"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if len(headers) != 1 {
+		t.Fatalf("callback calls = %d, want 1", len(headers))
+	}
+	if got := headers[0].Get("X-Quota-Remaining"); got != "41" {
+		t.Errorf("X-Quota-Remaining = %q, want %q", got, "41")
+	}
+}
+
+func TestWithSendInterceptor_Abort(t *testing.T) {
+	errQuota := errors.New("daily quota exceeded")
+	client, transport := newRecordingClient(t,
+		WithRetry(3, time.Millisecond),
+		WithSendInterceptor(func(ctx context.Context, req *http.Request) error {
+			return errQuota
+		}),
+	)
+
+	err := client.Send(context.Background(), "test message")
+	if !errors.Is(err, errQuota) {
+		t.Fatalf("Send() error = %v, want %v", err, errQuota)
+	}
+	if n := len(transport.Requests()); n != 0 {
+		t.Errorf("sent %d requests, want 0", n)
+	}
+}
+
+func TestWithSendInterceptor_MutateHeader(t *testing.T) {
+	client, transport := newRecordingClient(t,
+		WithSendInterceptor(func(ctx context.Context, req *http.Request) error {
+			req.Header.Set("X-Tenant", "acme")
+			return nil
+		}),
+	)
+
+	if err := client.Send(context.Background(), "test message"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if got := transport.Requests()[0].Header.Get("X-Tenant"); got != "acme" {
+		t.Errorf("X-Tenant header = %q, want %q", got, "acme")
+	}
+}
+
+func TestWithAPIVersion(t *testing.T) {
+	tests := []struct {
+		version    string
+		wantMethod string
+		wantURL    string
+	}{
+		{version: APIV1, wantMethod: http.MethodGet, wantURL: "https://bark.example.com/test-key/Deploy/done?sound=bell"},
+		{version: APIV2, wantMethod: http.MethodPost, wantURL: "https://bark.example.com/push"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			client, transport := newRecordingClient(t, WithAPIVersion(tt.version))
+
+			if err := client.Send(context.Background(), "done", WithTitle("Deploy"), WithSound("bell")); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			req := transport.Requests()[0]
+			if req.Method != tt.wantMethod || req.URL != tt.wantURL {
+				t.Errorf("request = %s %s, want %s %s", req.Method, req.URL, tt.wantMethod, tt.wantURL)
+			}
+			if req.Key != "test-key" || req.Title != "Deploy" || req.Body != "done" || req.Params["sound"] != "bell" {
+				t.Errorf("notification = %+v, want the same notification in both versions", req)
+			}
+		})
+	}
+}
+
+func TestWithAPIVersion_Unsupported(t *testing.T) {
+	if _, err := NewClient("", "test-key", WithAPIVersion("v3")); err == nil {
+		t.Error("NewClient() with unsupported api version error = nil, want error")
+	}
+}
+
+func TestWithLevelVolumes(t *testing.T) {
+	volumes := map[NotificationLevel]int{LevelCritical: 10, LevelTimeSensitive: 7}
+
+	tests := []struct {
+		name       string
+		opts       []Option
+		wantVolume string
+	}{
+		{name: "critical", opts: []Option{WithCriticalNotify()}, wantVolume: "10"},
+		{name: "time-sensitive", opts: []Option{WithTimeSensitive()}, wantVolume: "7"},
+		{name: "explicit volume", opts: []Option{WithCriticalNotify(), WithVolume(3)}, wantVolume: "3"},
+		{name: "unmapped level", opts: []Option{WithPassive()}, wantVolume: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, transport := newRecordingClient(t, WithLevelVolumes(volumes))
+
+			if err := client.Send(context.Background(), "db01 down", tt.opts...); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			if got := transport.Requests()[0].Params["volume"]; got != tt.wantVolume {
+				t.Errorf("volume = %q, want %q", got, tt.wantVolume)
+			}
+		})
+	}
+}
+
+func TestWithLevelVolumes_Invalid(t *testing.T) {
+	if _, err := NewClient("", "test-key", WithLevelVolumes(map[NotificationLevel]int{LevelCritical: 11})); err == nil {
+		t.Error("NewClient() with volume 11 error = nil, want error")
+	}
+}
+
+func TestWithLevelVolumes_CopiesMap(t *testing.T) {
+	volumes := map[NotificationLevel]int{LevelCritical: 10}
+	client, transport := newRecordingClient(t, WithLevelVolumes(volumes))
+
+	// Changing the map after NewClient does not affect the client
+	volumes[LevelCritical] = 2
+
+	if err := client.Send(context.Background(), "db01 down", WithCriticalNotify()); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if got := transport.Requests()[0].Params["volume"]; got != "10" {
+		t.Errorf("volume = %q, want %q", got, "10")
+	}
+}
+
+func TestWithTreatCancelAsSuccess(t *testing.T) {
+	tests := []struct {
+		name            string
+		opts            []ClientOption
+		wantErr         bool
+		wantDeadLetters int
+	}{
+		{name: "cancellation is an error by default", wantErr: true, wantDeadLetters: 1},
+		{name: "cancellation is a success", opts: []ClientOption{WithTreatCancelAsSuccess()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deadLetters int
+			opts := append([]ClientOption{
+				WithDeadLetter(func(ctx context.Context, body string, opts []Option, err error) { deadLetters++ }),
+			}, tt.opts...)
+			client, _ := newCaptureClient(t, opts...)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			err := client.Send(ctx, "shutting down")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if deadLetters != tt.wantDeadLetters {
+				t.Errorf("dead letter handler called %d times, want %d", deadLetters, tt.wantDeadLetters)
+			}
+		})
+	}
+}
+
+func TestWithMaxURLLength(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []ClientOption
+		body    string
+		wantErr error
+	}{
+		{name: "short body", opts: []ClientOption{WithMaxURLLength(0)}, body: "disk full"},
+		{name: "long body", opts: []ClientOption{WithMaxURLLength(0)}, body: strings.Repeat("x", 5000), wantErr: ErrURLTooLong},
+		{name: "custom limit", opts: []ClientOption{WithMaxURLLength(64)}, body: strings.Repeat("x", 100), wantErr: ErrURLTooLong},
+		{name: "POST mode is not limited", opts: []ClientOption{WithMaxURLLength(64), WithPostMode()}, body: strings.Repeat("x", 100)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, transport := newRecordingClient(t, append(tt.opts, WithRetry(2, time.Millisecond))...)
+
+			err := client.Send(context.Background(), tt.body)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Send() error = %v, want %v", err, tt.wantErr)
+			}
+
+			wantRequests := 1
+			if tt.wantErr != nil {
+				wantRequests = 0
+			}
+			if n := len(transport.Requests()); n != wantRequests {
+				t.Errorf("sent %d requests, want %d", n, wantRequests)
+			}
+		})
+	}
+}
+
+func TestWithHostHeader(t *testing.T) {
+	var gotHost string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHost = r.Host
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "test-key", WithHostHeader("bark.internal.example"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Send(context.Background(), "test message"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if gotHost != "bark.internal.example" {
+		t.Errorf("Host = %q, want %q", gotHost, "bark.internal.example")
+	}
+	if dialed := strings.TrimPrefix(server.URL, "http://"); gotHost == dialed {
+		t.Errorf("Host = %q, want it to differ from the dialed address", gotHost)
+	}
+}
+
+func TestWithHostHeader_Empty(t *testing.T) {
+	if _, err := NewClient("", "test-key", WithHostHeader("")); err == nil {
+		t.Error("NewClient() error = nil, want error")
+	}
+}
@@ -0,0 +1,35 @@
+package gobark
+
+import (
+	"fmt"
+	"time"
+)
+
+// WithClock sets the function the client reads the current time from for the
+// time stamps of WithBodyTimestamp and WithServerTimeStamp, the dedup window,
+// the active-ID TTL and Retry-After dates. The default is time.Now; set a fixed
+// clock to get predictable bodies and expiry in tests. Send durations and
+// context deadlines are still measured with the real clock.
+func WithClock(now func() time.Time) ClientOption {
+	return func(c *Client) error {
+		if now == nil {
+			return fmt.Errorf("clock must not be nil")
+		}
+		c.now = now
+		return nil
+	}
+}
+
+// WithBodyTimestamp prefixes every body with the local time formatted with
+// layout, such as time.Kitchen, so that notifications can be scanned by time.
+// The time stamp is added before the body transforms and encoding.
+// Use WithServerTimeStamp to stamp bodies with the server time instead.
+func WithBodyTimestamp(layout string) ClientOption {
+	return func(c *Client) error {
+		if layout == "" {
+			return fmt.Errorf("body timestamp layout must not be empty")
+		}
+		c.bodyTimestampLayout = layout
+		return nil
+	}
+}
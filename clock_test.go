@@ -0,0 +1,95 @@
+package gobark
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithBodyTimestamp(t *testing.T) {
+	fixed := time.Date(2024, 3, 15, 9, 30, 0, 0, time.UTC)
+	client, transport := newRecordingClient(t,
+		WithBodyTimestamp("15:04"),
+		WithClock(func() time.Time { return fixed }),
+		WithBodyTransform(func(body string) string { return "[" + body + "]" }),
+	)
+
+	if err := client.Send(context.Background(), "deploy finished"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	// The time stamp is part of the body seen by the body transforms
+	if got, want := transport.Requests()[0].Body, "[09:30 deploy finished]"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+func TestWithBodyTimestamp_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  ClientOption
+	}{
+		{name: "empty layout", opt: WithBodyTimestamp("")},
+		{name: "nil clock", opt: WithClock(nil)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewClient("", "test-key", tt.opt); err == nil {
+				t.Error("NewClient() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestWithClock_DedupWindow(t *testing.T) {
+	now := time.Date(2024, 3, 15, 9, 30, 0, 0, time.UTC)
+	client, transport := newRecordingClient(t,
+		WithDedupWindow(time.Minute),
+		WithClock(func() time.Time { return now }),
+	)
+	ctx := context.Background()
+
+	if err := client.Send(ctx, "disk full"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	// Still within the window of the client clock
+	now = now.Add(59 * time.Second)
+	if err := client.Send(ctx, "disk full"); !errors.Is(err, ErrDuplicateSuppressed) {
+		t.Fatalf("Send() error = %v, want ErrDuplicateSuppressed", err)
+	}
+
+	// The window expires once the client clock passes it
+	now = now.Add(2 * time.Second)
+	if err := client.Send(ctx, "disk full"); err != nil {
+		t.Fatalf("Send() after window error = %v", err)
+	}
+
+	if got := len(transport.Requests()); got != 2 {
+		t.Errorf("requests = %d, want 2", got)
+	}
+}
+
+func TestWithClock_ActiveIDTTL(t *testing.T) {
+	now := time.Date(2024, 3, 15, 9, 30, 0, 0, time.UTC)
+	client, _ := newRecordingClient(t,
+		WithActiveIDTracking(time.Hour),
+		WithClock(func() time.Time { return now }),
+	)
+
+	if err := client.Send(context.Background(), "in progress", WithID("job-1")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	now = now.Add(59 * time.Minute)
+	if got := client.ActiveIDs(); len(got) != 1 || got[0] != "job-1" {
+		t.Errorf("ActiveIDs() = %v, want [job-1]", got)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if got := client.ActiveIDs(); len(got) != 0 {
+		t.Errorf("ActiveIDs() after TTL = %v, want none", got)
+	}
+}
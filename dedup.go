@@ -0,0 +1,100 @@
+package gobark
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+// maxDedupEntries bounds the number of notifications remembered by the dedup cache.
+const maxDedupEntries = 1024
+
+// ErrDuplicateSuppressed is returned by Send when an identical notification
+// was already sent within the dedup window configured by WithDedupWindow.
+var ErrDuplicateSuppressed = errors.New("duplicate notification suppressed")
+
+// dedupCache remembers recently sent notifications by content hash.
+// Entries are kept in insertion order so the oldest can be evicted when the cache is full.
+type dedupCache struct {
+	mu      sync.Mutex
+	window  time.Duration
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+// dedupEntry is a single remembered notification.
+type dedupEntry struct {
+	hash   string
+	sentAt time.Time
+}
+
+func newDedupCache(window time.Duration) *dedupCache {
+	return &dedupCache{
+		window:  window,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// dedupHash returns the hash identifying the content of a notification.
+func dedupHash(title, body string) string {
+	sum := sha256.Sum256([]byte(title + "\x00" + body))
+	return hex.EncodeToString(sum[:])
+}
+
+// seen reports whether hash was recorded within the window.
+// If not, hash is recorded as sent at now.
+func (d *dedupCache) seen(hash string, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.evictExpired(now)
+
+	if _, ok := d.entries[hash]; ok {
+		return true
+	}
+
+	if d.order.Len() >= maxDedupEntries {
+		oldest := d.order.Front()
+		d.order.Remove(oldest)
+		delete(d.entries, oldest.Value.(*dedupEntry).hash)
+	}
+	d.entries[hash] = d.order.PushBack(&dedupEntry{hash: hash, sentAt: now})
+
+	return false
+}
+
+// forget removes hash from the cache, so that a failed send can be retried.
+func (d *dedupCache) forget(hash string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if elem, ok := d.entries[hash]; ok {
+		d.order.Remove(elem)
+		delete(d.entries, hash)
+	}
+}
+
+// reset removes every entry from the cache.
+func (d *dedupCache) reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.entries = make(map[string]*list.Element)
+	d.order.Init()
+}
+
+// evictExpired removes the entries older than the window.
+func (d *dedupCache) evictExpired(now time.Time) {
+	for elem := d.order.Front(); elem != nil; elem = d.order.Front() {
+		entry := elem.Value.(*dedupEntry)
+		if now.Sub(entry.sentAt) < d.window {
+			return
+		}
+		d.order.Remove(elem)
+		delete(d.entries, entry.hash)
+	}
+}
@@ -0,0 +1,90 @@
+package gobark
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestWithDedupWindow(t *testing.T) {
+	client, transport := newRecordingClient(t, WithDedupWindow(time.Minute))
+
+	ctx := context.Background()
+	if err := client.Send(ctx, "disk full", WithTitle("db01")); err != nil {
+		t.Fatalf("first Send() error = %v", err)
+	}
+	if err := client.Send(ctx, "disk full", WithTitle("db01")); !errors.Is(err, ErrDuplicateSuppressed) {
+		t.Errorf("second Send() error = %v, want %v", err, ErrDuplicateSuppressed)
+	}
+	if err := client.Send(ctx, "disk full", WithTitle("db02")); err != nil {
+		t.Errorf("Send() with different title error = %v", err)
+	}
+
+	if got := len(transport.Requests()); got != 2 {
+		t.Errorf("sent %d requests, want 2", got)
+	}
+}
+
+func TestWithDedupWindow_BodyTimestamp(t *testing.T) {
+	now := time.Date(2024, 3, 15, 9, 30, 0, 0, time.UTC)
+	client, transport := newRecordingClient(t,
+		WithDedupWindow(time.Hour),
+		WithBodyTimestamp(time.TimeOnly),
+		WithServerTimeStamp(time.DateTime),
+		// The clock advances a second on every read
+		WithClock(func() time.Time {
+			now = now.Add(time.Second)
+			return now
+		}),
+	)
+
+	ctx := context.Background()
+	if err := client.Send(ctx, "disk full"); err != nil {
+		t.Fatalf("first Send() error = %v", err)
+	}
+	if err := client.Send(ctx, "disk full"); !errors.Is(err, ErrDuplicateSuppressed) {
+		t.Errorf("second Send() error = %v, want %v", err, ErrDuplicateSuppressed)
+	}
+
+	if got := len(transport.Requests()); got != 1 {
+		t.Errorf("sent %d requests, want 1", got)
+	}
+}
+
+func TestDedupCache(t *testing.T) {
+	cache := newDedupCache(time.Minute)
+	now := time.Now()
+
+	if cache.seen("a", now) {
+		t.Error("seen(a) = true on first call, want false")
+	}
+	if !cache.seen("a", now.Add(30*time.Second)) {
+		t.Error("seen(a) = false within window, want true")
+	}
+	if cache.seen("a", now.Add(2*time.Minute)) {
+		t.Error("seen(a) = true after window, want false")
+	}
+
+	cache.forget("a")
+	if cache.seen("a", now.Add(2*time.Minute)) {
+		t.Error("seen(a) = true after forget, want false")
+	}
+}
+
+func TestDedupCache_Bounded(t *testing.T) {
+	cache := newDedupCache(time.Hour)
+	now := time.Now()
+
+	for i := 0; i < maxDedupEntries+10; i++ {
+		cache.seen(strconv.Itoa(i), now)
+	}
+
+	if got := cache.order.Len(); got != maxDedupEntries {
+		t.Errorf("cache size = %d, want %d", got, maxDedupEntries)
+	}
+	if cache.seen("0", now) {
+		t.Error("oldest entry was not evicted")
+	}
+}
@@ -1,3 +1,8 @@
 module github.com/xpzouying/gobark
 
 go 1.21
+
+require (
+	golang.org/x/text v0.14.0
+	golang.org/x/time v0.5.0
+)
@@ -0,0 +1,70 @@
+package gobark
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// hedgeResult is the outcome of one of the requests sent by doHedged.
+type hedgeResult struct {
+	statusCode int
+	err        error
+}
+
+// doHedged sends req, and if it has not completed within the hedging delay,
+// sends an identical second request concurrently. The first successful response
+// wins and the other request is cancelled. If both fail, the last error is returned.
+func (c *Client) doHedged(req *http.Request) (int, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	results := make(chan hedgeResult, 2)
+	launch := func() {
+		var result hedgeResult
+		defer func() { results <- result }()
+		defer recoverPanic(&result.err)
+
+		r, err := cloneRequest(ctx, req)
+		if err != nil {
+			result.err = err
+			return
+		}
+		result.statusCode, result.err = c.do(r)
+	}
+
+	go launch()
+	pending := 1
+
+	timer := time.NewTimer(c.hedgeDelay)
+	defer timer.Stop()
+
+	var last hedgeResult
+	for pending > 0 {
+		select {
+		case <-timer.C:
+			go launch()
+			pending++
+		case last = <-results:
+			pending--
+			if last.err == nil {
+				return last.statusCode, nil
+			}
+		}
+	}
+
+	return last.statusCode, last.err
+}
+
+// cloneRequest returns a copy of req bound to ctx with a fresh body.
+func cloneRequest(ctx context.Context, req *http.Request) (*http.Request, error) {
+	r := req.Clone(ctx)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = body
+	}
+	return r, nil
+}
@@ -0,0 +1,71 @@
+package gobark
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newSlowFirstServer returns a server whose first request stalls until it is cancelled.
+func newSlowFirstServer(t *testing.T, hits *int32) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(hits, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestWithHedging(t *testing.T) {
+	var hits int32
+	server := newSlowFirstServer(t, &hits)
+
+	client, err := NewClient(server.URL, "test-key", WithHedging(20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	if err := client.Send(context.Background(), "progress 50%", WithID("job-42")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Send() took %v, want the hedged request to win quickly", elapsed)
+	}
+	if got := atomic.LoadInt32(&hits); got != 2 {
+		t.Errorf("server hits = %d, want 2", got)
+	}
+}
+
+func TestWithHedging_RequiresID(t *testing.T) {
+	var hits int32
+	server := newSlowFirstServer(t, &hits)
+
+	client, err := NewClient(server.URL, "test-key", WithHedging(20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	if err := client.Send(ctx, "no id"); err == nil {
+		t.Error("Send() error = nil, want timeout without hedging")
+	}
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Errorf("server hits = %d, want 1", got)
+	}
+}
@@ -0,0 +1,81 @@
+package gobark
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// SendRecord describes a request sent by the client, as kept by WithSendHistory.
+type SendRecord struct {
+	// URL is the URL of the request, redacted if WithRedactedLogging is set.
+	URL string
+	// StatusCode is the status code of the response, or 0 if none was received.
+	StatusCode int
+	// Err is the error of the request, or nil if it succeeded.
+	Err error
+	// Time is when the request was sent.
+	Time time.Time
+}
+
+// sendHistory is a ring buffer of the most recent send records.
+type sendHistory struct {
+	mu      sync.Mutex
+	records []SendRecord
+	next    int
+	full    bool
+}
+
+// WithSendHistory keeps a record of the last n requests sent by the client,
+// including retries and failover attempts, which can be read with RecentSends
+// to diagnose intermittent failures.
+func WithSendHistory(n int) ClientOption {
+	return func(c *Client) error {
+		if n <= 0 {
+			return fmt.Errorf("send history size must be positive")
+		}
+		c.history = &sendHistory{records: make([]SendRecord, n)}
+		return nil
+	}
+}
+
+// RecentSends returns the requests kept by WithSendHistory, oldest first.
+// It returns nil unless WithSendHistory is set.
+func (c *Client) RecentSends() []SendRecord {
+	if c.history == nil {
+		return nil
+	}
+	return c.history.recent()
+}
+
+// add records r, replacing the oldest record if the history is full.
+func (h *sendHistory) add(r SendRecord) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.records[h.next] = r
+	h.next = (h.next + 1) % len(h.records)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// recent returns a copy of the records, oldest first.
+func (h *sendHistory) recent() []SendRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.full {
+		return append([]SendRecord(nil), h.records[:h.next]...)
+	}
+	return append(append([]SendRecord(nil), h.records[h.next:]...), h.records[:h.next]...)
+}
+
+// reset removes every record from the history.
+func (h *sendHistory) reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	clear(h.records)
+	h.next, h.full = 0, false
+}
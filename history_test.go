@@ -0,0 +1,55 @@
+package gobark
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithSendHistory(t *testing.T) {
+	client, transport := newRecordingClient(t, WithSendHistory(3))
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if err := client.Send(context.Background(), fmt.Sprintf("message %d", i)); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+	transport.StatusCode = http.StatusBadRequest
+	if err := client.Send(context.Background(), "rejected"); err == nil {
+		t.Fatal("Send() error = nil, want error")
+	}
+
+	records := client.RecentSends()
+	if len(records) != 3 {
+		t.Fatalf("RecentSends() returned %d records, want 3", len(records))
+	}
+	for i, wantBody := range []string{"message%203", "message%204", "rejected"} {
+		if !strings.HasSuffix(records[i].URL, "/"+wantBody) {
+			t.Errorf("record %d url = %q, want it to end with %q", i, records[i].URL, wantBody)
+		}
+		if records[i].Time.Before(start) {
+			t.Errorf("record %d time = %v, want after %v", i, records[i].Time, start)
+		}
+	}
+	if records[1].StatusCode != http.StatusOK || records[1].Err != nil {
+		t.Errorf("record 1 = %d/%v, want 200 without error", records[1].StatusCode, records[1].Err)
+	}
+	if records[2].StatusCode != http.StatusBadRequest || records[2].Err == nil {
+		t.Errorf("record 2 = %d/%v, want 400 with error", records[2].StatusCode, records[2].Err)
+	}
+}
+
+func TestRecentSends_WithoutHistory(t *testing.T) {
+	client, _ := newRecordingClient(t)
+
+	if err := client.Send(context.Background(), "test message"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if records := client.RecentSends(); records != nil {
+		t.Errorf("RecentSends() = %v, want nil", records)
+	}
+}
@@ -0,0 +1,61 @@
+package gobark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrAlreadySent is returned by SendOnce when a notification was already sent
+// with the same key, according to the idempotency store.
+var ErrAlreadySent = errors.New("notification already sent")
+
+// IdempotencyStore remembers the keys of the notifications sent with SendOnce.
+// Backing it with persistent storage, such as a file or a database, keeps
+// one-time notifications from being resent when the service restarts.
+// It must be safe for concurrent use.
+type IdempotencyStore interface {
+	// Seen reports whether key was marked as sent.
+	Seen(key string) bool
+	// Mark records that the notification with key was sent.
+	Mark(key string)
+}
+
+// WithIdempotencyStore sets the store used by SendOnce to remember sent notifications.
+func WithIdempotencyStore(store IdempotencyStore) ClientOption {
+	return func(c *Client) error {
+		if store == nil {
+			return fmt.Errorf("idempotency store must not be nil")
+		}
+		c.idempotencyStore = store
+		return nil
+	}
+}
+
+// SendOnce sends a notification like Send, unless one was already sent with key:
+// it then returns ErrAlreadySent without sending. The key is marked in the store
+// set by WithIdempotencyStore once the notification is delivered, so a failed send,
+// or one cancelled under WithTreatCancelAsSuccess, can be tried again. Concurrent calls with the same key may both send.
+func (c *Client) SendOnce(ctx context.Context, key, body string, opts ...Option) error {
+	if c.idempotencyStore == nil {
+		return fmt.Errorf("idempotency store not set, see WithIdempotencyStore")
+	}
+	if key == "" {
+		return fmt.Errorf("idempotency key is required")
+	}
+
+	if c.idempotencyStore.Seen(key) {
+		return ErrAlreadySent
+	}
+	statusCode, err := c.SendStatus(ctx, body, opts...)
+	if err != nil {
+		return err
+	}
+	// Under WithTreatCancelAsSuccess, a cancelled send returns nil without
+	// delivering anything, so only a 200 response marks the key
+	if statusCode == http.StatusOK {
+		c.idempotencyStore.Mark(key)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package gobark
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// memoryStore is an IdempotencyStore that keeps the keys in memory.
+type memoryStore struct {
+	mu   sync.Mutex
+	keys map[string]bool
+}
+
+func (s *memoryStore) Seen(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.keys[key]
+}
+
+func (s *memoryStore) Mark(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[key] = true
+}
+
+func TestSendOnce(t *testing.T) {
+	store := &memoryStore{keys: map[string]bool{}}
+	client, transport := newRecordingClient(t, WithIdempotencyStore(store))
+
+	ctx := context.Background()
+	if err := client.SendOnce(ctx, "migration-42", "migration done"); err != nil {
+		t.Fatalf("SendOnce() error = %v", err)
+	}
+	if err := client.SendOnce(ctx, "migration-42", "migration done"); !errors.Is(err, ErrAlreadySent) {
+		t.Errorf("second SendOnce() error = %v, want %v", err, ErrAlreadySent)
+	}
+	if err := client.SendOnce(ctx, "migration-43", "migration done"); err != nil {
+		t.Fatalf("SendOnce() with another key error = %v", err)
+	}
+
+	if n := len(transport.Requests()); n != 2 {
+		t.Errorf("sent %d requests, want 2", n)
+	}
+}
+
+func TestSendOnce_FailureNotMarked(t *testing.T) {
+	store := &memoryStore{keys: map[string]bool{}}
+	rejecting := true
+	client, transport := newRecordingClient(t,
+		WithIdempotencyStore(store),
+		WithSendInterceptor(func(ctx context.Context, req *http.Request) error {
+			if rejecting {
+				return errors.New("rejected")
+			}
+			return nil
+		}),
+	)
+
+	ctx := context.Background()
+	if err := client.SendOnce(ctx, "migration-42", "migration done"); err == nil {
+		t.Fatal("SendOnce() error = nil, want error")
+	}
+	if store.Seen("migration-42") {
+		t.Error("key marked after a failed send, want it left unmarked")
+	}
+
+	rejecting = false
+	if err := client.SendOnce(ctx, "migration-42", "migration done"); err != nil {
+		t.Fatalf("SendOnce() retry error = %v", err)
+	}
+	if n := len(transport.Requests()); n != 1 {
+		t.Errorf("sent %d requests, want 1", n)
+	}
+}
+
+func TestSendOnce_CancelAsSuccessNotMarked(t *testing.T) {
+	store := &memoryStore{keys: map[string]bool{}}
+	ctx, cancel := context.WithCancel(context.Background())
+	// The first send is cancelled, e.g. by a shutdown, just before its request goes out
+	cancelling := true
+	client, transport := newRecordingClient(t,
+		WithIdempotencyStore(store),
+		WithTreatCancelAsSuccess(),
+		WithSendInterceptor(func(reqCtx context.Context, req *http.Request) error {
+			if cancelling {
+				cancel()
+				return ctx.Err()
+			}
+			return nil
+		}),
+	)
+
+	if err := client.SendOnce(ctx, "migration-42", "migration done"); err != nil {
+		t.Fatalf("cancelled SendOnce() error = %v, want nil", err)
+	}
+	if store.Seen("migration-42") {
+		t.Fatal("key marked after a cancelled send, want it unmarked")
+	}
+
+	cancelling = false
+	if err := client.SendOnce(context.Background(), "migration-42", "migration done"); err != nil {
+		t.Fatalf("SendOnce() retry error = %v", err)
+	}
+	if n := len(transport.Requests()); n != 1 {
+		t.Errorf("sent %d requests, want 1", n)
+	}
+	if !store.Seen("migration-42") {
+		t.Error("key not marked after delivery")
+	}
+}
+
+func TestSendOnce_WithoutStore(t *testing.T) {
+	client, _ := newRecordingClient(t)
+
+	if err := client.SendOnce(context.Background(), "migration-42", "migration done"); err == nil {
+		t.Error("SendOnce() without store error = nil, want error")
+	}
+}
@@ -0,0 +1,102 @@
+package gobark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+)
+
+// ErrInvalidKey is returned when a key does not match the pattern set by WithKeyPattern.
+var ErrInvalidKey = errors.New("key does not match the expected pattern")
+
+// KeyProvider supplies the Bark key when a notification is sent.
+// It allows the key to be fetched from a secret store and rotated
+// without reconstructing the client.
+type KeyProvider interface {
+	Key(ctx context.Context) (string, error)
+}
+
+// NewClientWithKeyProvider creates a new Bark client with the specified base URL
+// that resolves the key from p on every send.
+// Additional options can be provided to customize the client.
+func NewClientWithKeyProvider(baseURL string, p KeyProvider, opts ...ClientOption) (*Client, error) {
+	if p == nil {
+		return nil, fmt.Errorf("key provider is required")
+	}
+
+	return newClient(baseURL, "", p, opts)
+}
+
+// WithKeyPattern requires keys to match re, for example ^[A-Za-z0-9]{22}$ for
+// 22-character base62 keys, to catch copy-paste errors early. NewClient fails
+// with ErrInvalidKey if the key does not match; keys returned by a KeyProvider
+// are checked on every send. The pattern should be anchored to match the whole key.
+func WithKeyPattern(re *regexp.Regexp) ClientOption {
+	return func(c *Client) error {
+		if re == nil {
+			return fmt.Errorf("key pattern must not be nil")
+		}
+		c.keyPattern = re
+		return nil
+	}
+}
+
+// WithKeyInHeader sends the key in the HTTP header headerName instead of the URL
+// path or POST payload, which keeps it out of access logs. It requires a server,
+// or an authenticating gateway in front of it, that reads the key from that header;
+// the stock Bark server only accepts the key in the path or payload.
+// Batches sent with SendToKeys still list their keys in the payload.
+func WithKeyInHeader(headerName string) ClientOption {
+	return func(c *Client) error {
+		if headerName == "" {
+			return fmt.Errorf("key header name must not be empty")
+		}
+		c.keyHeader = http.CanonicalHeaderKey(headerName)
+		return nil
+	}
+}
+
+// validateKey checks key against the key pattern, if any.
+// The key itself is left out of the error, as it is a secret.
+func (c *Client) validateKey(key string) error {
+	if c.keyPattern != nil && !c.keyPattern.MatchString(key) {
+		return fmt.Errorf("%w %s", ErrInvalidKey, c.keyPattern)
+	}
+	return nil
+}
+
+// resolveKey returns the key to send the current notification with: the key
+// of a SendToKeysStream send, or else the static key or the provider's key.
+func (c *Client) resolveKey(ctx context.Context) (string, error) {
+	if key, ok := ctx.Value(keyOverrideKey{}).(string); ok {
+		if key == "" {
+			return "", ErrKeyRequired
+		}
+		if err := c.validateKey(key); err != nil {
+			return "", err
+		}
+		return key, nil
+	}
+
+	if c.keyProvider == nil {
+		if c.key == "" {
+			return "", ErrKeyRequired
+		}
+		return c.key, nil
+	}
+
+	key, err := c.keyProvider.Key(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve key: %w", err)
+	}
+	if key == "" {
+		return "", fmt.Errorf("key provider returned an empty key: %w", ErrKeyRequired)
+	}
+	if err := c.validateKey(key); err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
@@ -0,0 +1,168 @@
+package gobark
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/xpzouying/gobark/barktest"
+)
+
+// rotatingKeyProvider returns the next key from keys on each call.
+type rotatingKeyProvider struct {
+	keys  []string
+	calls int
+}
+
+func (p *rotatingKeyProvider) Key(ctx context.Context) (string, error) {
+	key := p.keys[p.calls%len(p.keys)]
+	p.calls++
+	return key, nil
+}
+
+// failingKeyProvider always fails to return a key.
+type failingKeyProvider struct{}
+
+func (failingKeyProvider) Key(ctx context.Context) (string, error) {
+	return "", errors.New("secret store unavailable")
+}
+
+func TestNewClientWithKeyProvider(t *testing.T) {
+	transport := &barktest.RecordingTransport{}
+	provider := &rotatingKeyProvider{keys: []string{"key-1", "key-2"}}
+	client, err := NewClientWithKeyProvider("", provider, WithHTTPClient(&http.Client{Transport: transport}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := client.Send(context.Background(), "test message"); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	wantKeys := []string{"key-1", "key-2", "key-1"}
+	for i, req := range transport.Requests() {
+		if req.Key != wantKeys[i] {
+			t.Errorf("request %d key = %q, want %q", i, req.Key, wantKeys[i])
+		}
+	}
+}
+
+func TestNewClientWithKeyProvider_Error(t *testing.T) {
+	transport := &barktest.RecordingTransport{}
+	client, err := NewClientWithKeyProvider("", failingKeyProvider{}, WithHTTPClient(&http.Client{Transport: transport}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Send(context.Background(), "test message"); err == nil {
+		t.Error("Send() error = nil, want key provider error")
+	}
+	if len(transport.Requests()) != 0 {
+		t.Error("request was sent, want none")
+	}
+}
+
+func TestNewClientWithKeyProvider_Nil(t *testing.T) {
+	if _, err := NewClientWithKeyProvider("", nil); err == nil {
+		t.Error("NewClientWithKeyProvider(nil) error = nil, want error")
+	}
+}
+
+func TestSend_KeyClearedAfterConstruction(t *testing.T) {
+	client, transport := newRecordingClient(t)
+	client.key = ""
+
+	if err := client.Send(context.Background(), "test message"); !errors.Is(err, ErrKeyRequired) {
+		t.Errorf("Send() error = %v, want %v", err, ErrKeyRequired)
+	}
+	if len(transport.Requests()) != 0 {
+		t.Error("request was sent, want none")
+	}
+}
+
+func TestNewClient_KeyRequired(t *testing.T) {
+	if _, err := NewClient("", ""); !errors.Is(err, ErrKeyRequired) {
+		t.Errorf("NewClient() error = %v, want %v", err, ErrKeyRequired)
+	}
+}
+
+func TestWithKeyPattern(t *testing.T) {
+	pattern := regexp.MustCompile(`^[A-Za-z0-9]{22}$`)
+
+	tests := []struct {
+		name    string
+		key     string
+		wantErr error
+	}{
+		{name: "matching key", key: "a1B2c3D4e5F6g7H8i9J0kL"},
+		{name: "truncated key", key: "a1B2c3D4e5F6g7H8i9J0k", wantErr: ErrInvalidKey},
+		{name: "key with trailing space", key: "a1B2c3D4e5F6g7H8i9J0kL ", wantErr: ErrInvalidKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewClient("", tt.key, WithKeyPattern(pattern))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("NewClient() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWithKeyPattern_KeyProvider(t *testing.T) {
+	transport := &barktest.RecordingTransport{}
+	provider := &rotatingKeyProvider{keys: []string{"abc123", "not a key"}}
+	client, err := NewClientWithKeyProvider("", provider,
+		WithHTTPClient(&http.Client{Transport: transport}),
+		WithKeyPattern(regexp.MustCompile(`^[a-z0-9]+$`)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Send(context.Background(), "first"); err != nil {
+		t.Errorf("Send() with matching key error = %v", err)
+	}
+	if err := client.Send(context.Background(), "second"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Send() with mismatching key error = %v, want %v", err, ErrInvalidKey)
+	}
+	if n := len(transport.Requests()); n != 1 {
+		t.Errorf("sent %d requests, want 1", n)
+	}
+}
+
+func TestWithKeyInHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []ClientOption
+		wantURL string
+	}{
+		{name: "GET", wantURL: "https://bark.example.com/Deploy/done"},
+		{name: "POST", opts: []ClientOption{WithPostMode()}, wantURL: "https://bark.example.com/push"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, transport := newRecordingClient(t, append([]ClientOption{WithKeyInHeader("x-bark-key")}, tt.opts...)...)
+
+			if err := client.Send(context.Background(), "done", WithTitle("Deploy")); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			req := transport.Requests()[0]
+			if got := req.Header.Get("X-Bark-Key"); got != "test-key" {
+				t.Errorf("X-Bark-Key header = %q, want %q", got, "test-key")
+			}
+			if req.URL != tt.wantURL {
+				t.Errorf("url = %q, want %q", req.URL, tt.wantURL)
+			}
+			if req.Method == http.MethodPost && req.Key != "" {
+				t.Errorf("payload device key = %q, want none", req.Key)
+			}
+		})
+	}
+}
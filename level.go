@@ -0,0 +1,60 @@
+package gobark
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrBelowMinLevel is returned by Send when a notification is below the
+// minimum level set by WithMinLevel and was not sent.
+var ErrBelowMinLevel = errors.New("notification below minimum level suppressed")
+
+// rank orders notification levels by importance: passive, active, time-sensitive
+// and critical. A notification without a level is active; so are unknown levels.
+func (l NotificationLevel) rank() int {
+	switch l {
+	case LevelPassive:
+		return 0
+	case LevelTimeSensitive:
+		return 2
+	case LevelCritical:
+		return 3
+	default:
+		return 1
+	}
+}
+
+// WithMinLevel suppresses notifications less important than level, from least to
+// most important: LevelPassive, LevelActive, LevelTimeSensitive and LevelCritical.
+// Suppressed sends return ErrBelowMinLevel. Notifications without a level are active.
+func WithMinLevel(level NotificationLevel) ClientOption {
+	return func(c *Client) error {
+		switch level {
+		case LevelPassive, LevelActive, LevelTimeSensitive, LevelCritical:
+		default:
+			return fmt.Errorf("unsupported notification level %q", level)
+		}
+		c.minLevel = level
+		return nil
+	}
+}
+
+// WithDefaultLevel sets the level of notifications sent without a level option,
+// such as WithTimeSensitive or WithPassive. A LevelCritical default makes them
+// critical alerts, as with WithCriticalNotify.
+func WithDefaultLevel(level NotificationLevel) ClientOption {
+	return func(c *Client) error {
+		switch level {
+		case LevelPassive, LevelActive, LevelTimeSensitive, LevelCritical:
+		default:
+			return fmt.Errorf("unsupported notification level %q", level)
+		}
+		c.defaultLevel = level
+		return nil
+	}
+}
+
+// belowMinLevel reports whether n is less important than the minimum level.
+func (c *Client) belowMinLevel(n *Notification) bool {
+	return c.minLevel != "" && n.effectiveLevel().rank() < c.minLevel.rank()
+}
@@ -0,0 +1,84 @@
+package gobark
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestWithMinLevel(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr error
+	}{
+		{name: "passive suppressed", opts: []Option{WithPassive()}, wantErr: ErrBelowMinLevel},
+		{name: "active suppressed", opts: nil, wantErr: ErrBelowMinLevel},
+		{name: "time-sensitive sent", opts: []Option{WithTimeSensitive()}},
+		{name: "critical sent", opts: []Option{WithCriticalNotify()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deadLetters int
+			client, transport := newRecordingClient(t,
+				WithMinLevel(LevelTimeSensitive),
+				WithDeadLetter(func(ctx context.Context, body string, opts []Option, err error) { deadLetters++ }),
+			)
+
+			err := client.Send(context.Background(), "test message", tt.opts...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Send() error = %v, want %v", err, tt.wantErr)
+			}
+
+			wantRequests := 1
+			if tt.wantErr != nil {
+				wantRequests = 0
+			}
+			if n := len(transport.Requests()); n != wantRequests {
+				t.Errorf("sent %d requests, want %d", n, wantRequests)
+			}
+			if deadLetters != 0 {
+				t.Errorf("dead letter handler called %d times, want 0", deadLetters)
+			}
+		})
+	}
+}
+
+func TestWithMinLevel_Unsupported(t *testing.T) {
+	if _, err := NewClient("", "test-key", WithMinLevel("urgent")); err == nil {
+		t.Error("NewClient() with unsupported level error = nil, want error")
+	}
+}
+
+func TestWithDefaultLevel(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []Option
+		wantLevel string
+	}{
+		{name: "default level", opts: nil, wantLevel: "timeSensitive"},
+		{name: "explicit passive", opts: []Option{WithPassive()}, wantLevel: "passive"},
+		{name: "explicit critical", opts: []Option{WithCriticalNotify()}, wantLevel: "critical"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, transport := newRecordingClient(t, WithDefaultLevel(LevelTimeSensitive))
+
+			if err := client.Send(context.Background(), "test message", tt.opts...); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			if got := transport.Requests()[0].Params["level"]; got != tt.wantLevel {
+				t.Errorf("level = %q, want %q", got, tt.wantLevel)
+			}
+		})
+	}
+}
+
+func TestWithDefaultLevel_Unsupported(t *testing.T) {
+	if _, err := NewClient("", "test-key", WithDefaultLevel("urgent")); err == nil {
+		t.Error("NewClient() with unsupported level error = nil, want error")
+	}
+}
@@ -0,0 +1,47 @@
+package gobark
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// SendMetrics describes a request sent by the client, as reported to a SendMetricsObserver.
+type SendMetrics struct {
+	// StatusCode is the status code of the response, or 0 if none was received.
+	StatusCode int
+	// Err is the error of the request, or nil if it succeeded.
+	Err error
+	// Duration is the time from sending the request to receiving the response headers or failing.
+	Duration time.Duration
+	// Size is the encoded size of the notification in bytes: the length of the URL
+	// of a GET request, or the length of the body of a POST request, after compression.
+	Size int
+}
+
+// SendMetricsObserver receives the metrics of every request sent by a client,
+// including retries and failover attempts, for example to track payload sizes
+// approaching the APNs limit.
+type SendMetricsObserver interface {
+	ObserveSend(ctx context.Context, m SendMetrics)
+}
+
+// WithSendMetrics reports the metrics of every request to o.
+func WithSendMetrics(o SendMetricsObserver) ClientOption {
+	return func(c *Client) error {
+		if o == nil {
+			return fmt.Errorf("metrics observer must not be nil")
+		}
+		c.metrics = o
+		return nil
+	}
+}
+
+// payloadSize returns the encoded size of the notification carried by req.
+func payloadSize(req *http.Request) int {
+	if req.Method == http.MethodGet {
+		return len(req.URL.String())
+	}
+	return int(req.ContentLength)
+}
@@ -0,0 +1,76 @@
+package gobark
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeMetrics records the metrics it receives.
+type fakeMetrics struct {
+	observed []SendMetrics
+}
+
+func (m *fakeMetrics) ObserveSend(ctx context.Context, sm SendMetrics) {
+	m.observed = append(m.observed, sm)
+}
+
+func TestWithSendMetrics(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []ClientOption
+		body string
+	}{
+		{name: "GET", body: "disk full"},
+		{name: "POST", opts: []ClientOption{WithPostMode()}, body: "disk full"},
+		{name: "gzipped POST", opts: []ClientOption{WithPostMode(), WithAutoGzip(64)}, body: strings.Repeat("log line\n", 100)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var wantSize int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodGet {
+					wantSize = len("http://" + r.Host + r.URL.RequestURI())
+					return
+				}
+				data, _ := io.ReadAll(r.Body)
+				wantSize = len(data)
+			}))
+			defer server.Close()
+
+			metrics := &fakeMetrics{}
+			client, err := NewClient(server.URL, "test-key", append([]ClientOption{WithSendMetrics(metrics)}, tt.opts...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := client.Send(context.Background(), tt.body, WithTitle("db01")); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			if len(metrics.observed) != 1 {
+				t.Fatalf("observed %d sends, want 1", len(metrics.observed))
+			}
+			got := metrics.observed[0]
+			if got.Size != wantSize {
+				t.Errorf("Size = %d, want %d", got.Size, wantSize)
+			}
+			if got.StatusCode != http.StatusOK || got.Err != nil {
+				t.Errorf("StatusCode, Err = %d, %v, want %d, nil", got.StatusCode, got.Err, http.StatusOK)
+			}
+			if got.Duration <= 0 {
+				t.Errorf("Duration = %v, want positive", got.Duration)
+			}
+		})
+	}
+}
+
+func TestWithSendMetrics_Nil(t *testing.T) {
+	if _, err := NewClient("", "test-key", WithSendMetrics(nil)); err == nil {
+		t.Error("NewClient() error = nil, want error")
+	}
+}
@@ -0,0 +1,149 @@
+package gobark
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NewNotification creates a notification with the given body and options.
+// It can be inspected before sending, for example to compute its fingerprint.
+func NewNotification(body string, opts ...Option) *Notification {
+	n := &Notification{
+		title: defaultTitle,
+		body:  body,
+	}
+
+	for _, opt := range opts {
+		opt(n)
+	}
+
+	if n.titleDelimiter != "" {
+		if title, subtitle, ok := strings.Cut(n.title, n.titleDelimiter); ok {
+			n.title, n.subtitle = strings.TrimSpace(title), strings.TrimSpace(subtitle)
+		}
+	}
+	if n.body == "" && len(n.bodyFields) > 0 {
+		n.body = formatFields(n.bodyFields)
+	}
+	if n.autoID && n.id == "" {
+		n.id = n.Fingerprint()
+	}
+
+	return n
+}
+
+// formatFields formats fields as "key: value" lines sorted by key.
+func formatFields(fields map[string]string) string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	lines := make([]string, len(keys))
+	for i, key := range keys {
+		lines[i] = key + ": " + fields[key]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Fingerprint returns a stable hex-encoded hash of the notification's meaningful
+// content: its title, subtitle, body, level, sound and group. Notifications with
+// the same content share a fingerprint, which makes it suitable for deduplication,
+// log correlation and idempotency keys.
+func (n *Notification) Fingerprint() string {
+	h := sha256.New()
+	for _, field := range []string{n.title, n.subtitle, n.body, string(n.effectiveLevel()), n.sound, n.group} {
+		// Separate fields with a NUL byte so that shifting content between
+		// adjacent fields changes the hash
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Equal reports whether n and other have identical fields.
+func (n *Notification) Equal(other *Notification) bool {
+	return n.Diff(other) == ""
+}
+
+// Diff returns a readable description of the fields that differ between n and other,
+// one field per line in the form `title: "a" != "b"`, or an empty string if they are equal.
+// It is intended for test failure messages.
+func (n *Notification) Diff(other *Notification) string {
+	if n == nil || other == nil {
+		if n == other {
+			return ""
+		}
+		return fmt.Sprintf("notification: %v != %v", n != nil, other != nil)
+	}
+
+	var diff strings.Builder
+	otherFields := other.fields()
+	for i, field := range n.fields() {
+		if field.value != otherFields[i].value {
+			fmt.Fprintf(&diff, "%s: %q != %q\n", field.name, field.value, otherFields[i].value)
+		}
+	}
+
+	return diff.String()
+}
+
+// formatVolume formats a volume set by WithVolume, or returns "unset".
+func formatVolume(volume *int) string {
+	if volume == nil {
+		return "unset"
+	}
+	return strconv.Itoa(*volume)
+}
+
+// formatArchive formats an archive setting from WithArchiveGroups, or returns "unset".
+func formatArchive(archive *bool) string {
+	if archive == nil {
+		return "unset"
+	}
+	return strconv.FormatBool(*archive)
+}
+
+// notificationField is the name and formatted value of a notification field.
+type notificationField struct {
+	name  string
+	value string
+}
+
+// fields returns every field of the notification in a fixed order.
+func (n *Notification) fields() []notificationField {
+	return []notificationField{
+		{"title", n.title},
+		{"subtitle", n.subtitle},
+		{"titleDelimiter", n.titleDelimiter},
+		{"body", n.body},
+		{"icon", n.icon},
+		{"image", n.image},
+		{"sound", n.sound},
+		{"soundPreferences", strings.Join(n.soundPreferences, ",")},
+		{"haptic", n.haptic},
+		{"soundRepeat", strconv.Itoa(n.soundRepeat)},
+		{"bodyFields", formatFields(n.bodyFields)},
+		{"level", string(n.level)},
+		{"isCritical", strconv.FormatBool(n.isCritical)},
+		{"url", n.url},
+		{"copyURL", strconv.FormatBool(n.copyURL)},
+		{"group", n.group},
+		{"category", n.category},
+		{"id", n.id},
+		{"collapseID", n.collapseID},
+		{"contentAvailable", strconv.FormatBool(n.contentAvailable)},
+		{"delete", strconv.FormatBool(n.delete)},
+		{"autoID", strconv.FormatBool(n.autoID)},
+		{"expiration", n.expiration.String()},
+		{"volume", formatVolume(n.volume)},
+		{"archive", formatArchive(n.archive)},
+		{"bodyEncoding", n.bodyEncoding},
+	}
+}
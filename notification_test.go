@@ -0,0 +1,206 @@
+package gobark
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNotification_Fingerprint(t *testing.T) {
+	base := []Option{WithTitle("Backup"), WithSubtitle("db01"), WithGroup("ops"), WithSound("bell")}
+
+	tests := []struct {
+		name  string
+		a, b  *Notification
+		equal bool
+	}{
+		{
+			name:  "identical notifications",
+			a:     NewNotification("backup finished", base...),
+			b:     NewNotification("backup finished", base...),
+			equal: true,
+		},
+		{
+			name:  "options in different order",
+			a:     NewNotification("backup finished", WithTitle("Backup"), WithGroup("ops")),
+			b:     NewNotification("backup finished", WithGroup("ops"), WithTitle("Backup")),
+			equal: true,
+		},
+		{
+			name: "different body",
+			a:    NewNotification("backup finished", base...),
+			b:    NewNotification("backup failed", base...),
+		},
+		{
+			name: "different level",
+			a:    NewNotification("backup finished", base...),
+			b:    NewNotification("backup finished", append(base, WithTimeSensitive())...),
+		},
+		{
+			name: "content shifted between fields",
+			a:    NewNotification("b", WithTitle("a"), WithSubtitle("")),
+			b:    NewNotification("", WithTitle("a"), WithSubtitle("b")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.Fingerprint() == tt.b.Fingerprint(); got != tt.equal {
+				t.Errorf("fingerprints equal = %v, want %v", got, tt.equal)
+			}
+		})
+	}
+}
+
+func TestNotification_FingerprintStable(t *testing.T) {
+	// The fingerprint must not change across runs or releases
+	n := NewNotification("hello", WithTitle("greeting"))
+	const want = "59a9485d8c2ebf98199ef85e147aed61fca6408ca51b002dc2d29b45e1a8d453"
+	if got := n.Fingerprint(); got != want {
+		t.Errorf("Fingerprint() = %s, want %s", got, want)
+	}
+}
+
+func TestWithAutoID(t *testing.T) {
+	a := NewNotification("disk full", WithTitle("db01"), WithAutoID())
+	b := NewNotification("disk full", WithTitle("db01"), WithAutoID())
+	c := NewNotification("disk ok", WithTitle("db01"), WithAutoID())
+
+	if a.id == "" {
+		t.Fatal("auto id is empty")
+	}
+	if a.id != b.id {
+		t.Errorf("identical content got ids %q and %q, want the same", a.id, b.id)
+	}
+	if a.id == c.id {
+		t.Errorf("different content got the same id %q", a.id)
+	}
+
+	if got := NewNotification("disk full", WithAutoID(), WithID("db01-disk")).id; got != "db01-disk" {
+		t.Errorf("id = %q, want explicit id %q", got, "db01-disk")
+	}
+}
+
+func TestNotification_Equal(t *testing.T) {
+	a := NewNotification("disk full", WithTitle("db01"), WithSound("bell"), WithURL("https://example.com"))
+	b := NewNotification("disk full", WithTitle("db01"), WithSound("bell"), WithURL("https://example.com"))
+	c := NewNotification("disk full", WithTitle("db01"), WithSound("alarm"), WithCopyURL())
+
+	if !a.Equal(b) {
+		t.Errorf("Equal() = false for identical notifications, diff:\n%s", a.Diff(b))
+	}
+	if a.Equal(c) {
+		t.Error("Equal() = true for different notifications")
+	}
+	if a.Equal(nil) {
+		t.Error("Equal(nil) = true, want false")
+	}
+}
+
+func TestNotification_Diff(t *testing.T) {
+	a := NewNotification("disk full", WithTitle("db01"), WithSound("bell"), WithURL("https://example.com"))
+	b := NewNotification("disk full", WithTitle("db02"), WithSound("bell"), WithURL("https://example.com"), WithCopyURL())
+
+	want := "title: \"db01\" != \"db02\"\n" +
+		"copyURL: \"false\" != \"true\"\n"
+	if got := a.Diff(b); got != want {
+		t.Errorf("Diff() =\n%s\nwant\n%s", got, want)
+	}
+
+	if got := a.Diff(a); got != "" {
+		t.Errorf("Diff() of a notification with itself = %q, want empty", got)
+	}
+}
+
+func TestWithFields(t *testing.T) {
+	fields := map[string]string{"host": "db01", "cpu": "92%", "disk": "71%"}
+
+	tests := []struct {
+		name     string
+		body     string
+		wantBody string
+	}{
+		{name: "formats fields without a body", body: "", wantBody: "cpu: 92%\ndisk: 71%\nhost: db01"},
+		{name: "explicit body wins", body: "db01 is overloaded", wantBody: "db01 is overloaded"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, transport := newRecordingClient(t)
+
+			if err := client.Send(context.Background(), tt.body, WithFields(fields)); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			if got := transport.Requests()[0].Body; got != tt.wantBody {
+				t.Errorf("body = %q, want %q", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestWithSplitTitle(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         []Option
+		wantTitle    string
+		wantSubtitle string
+	}{
+		{name: "split", opts: []Option{WithTitle("Build | main branch"), WithSplitTitle("|")}, wantTitle: "Build", wantSubtitle: "main branch"},
+		{name: "split before title", opts: []Option{WithSplitTitle("|"), WithTitle("Build | main branch")}, wantTitle: "Build", wantSubtitle: "main branch"},
+		{name: "first delimiter only", opts: []Option{WithTitle("Build | main | 42"), WithSplitTitle("|")}, wantTitle: "Build", wantSubtitle: "main | 42"},
+		{name: "no delimiter", opts: []Option{WithTitle("Build"), WithSubtitle("main"), WithSplitTitle("|")}, wantTitle: "Build", wantSubtitle: "main"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, transport := newRecordingClient(t)
+
+			if err := client.Send(context.Background(), "passed", tt.opts...); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			req := transport.Requests()[0]
+			if req.Title != tt.wantTitle || req.Subtitle != tt.wantSubtitle {
+				t.Errorf("title, subtitle = %q, %q, want %q, %q", req.Title, req.Subtitle, tt.wantTitle, tt.wantSubtitle)
+			}
+		})
+	}
+}
+
+func TestNotification_Validate(t *testing.T) {
+	unknownLevel := NewNotification("disk full")
+	unknownLevel.level = "urgent"
+
+	tests := []struct {
+		name    string
+		n       *Notification
+		wantErr bool
+	}{
+		{name: "valid", n: NewNotification("disk full", WithCriticalNotify(), WithVolume(5), WithURL("https://example.com"), WithCopyURL())},
+		{name: "empty body", n: NewNotification("", WithTitle("db01")), wantErr: true},
+		{name: "copy url without url", n: NewNotification("disk full", WithCopyURL()), wantErr: true},
+		{name: "volume too loud", n: NewNotification("disk full", WithCriticalNotify(), WithVolume(11)), wantErr: true},
+		{name: "negative volume", n: NewNotification("disk full", WithCriticalNotify(), WithVolume(-1)), wantErr: true},
+		{name: "negative sound repeat", n: NewNotification("disk full", WithSoundRepeat(-1)), wantErr: true},
+		{name: "unknown level", n: unknownLevel, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.n.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSend_InvalidNotification(t *testing.T) {
+	client, transport := newRecordingClient(t)
+
+	if err := client.Send(context.Background(), "disk full", WithVolume(11)); err == nil {
+		t.Error("Send() with volume 11 error = nil, want error")
+	}
+	if n := len(transport.Requests()); n != 0 {
+		t.Errorf("sent %d requests, want 0", n)
+	}
+}
@@ -0,0 +1,17 @@
+package gobark
+
+import "context"
+
+// Notifier sends notifications. It is implemented by *Client, and lets code that
+// sends notifications depend on an interface that tests can replace with a fake.
+type Notifier interface {
+	// Send sends a notification with body, see Client.Send.
+	Send(ctx context.Context, body string, opts ...Option) error
+	// SendStatus sends a notification with body and returns the response
+	// status code, see Client.SendStatus.
+	SendStatus(ctx context.Context, body string, opts ...Option) (int, error)
+	// SendAll sends several notifications at once, see Client.SendAll.
+	SendAll(ctx context.Context, notifs []*Notification) error
+}
+
+var _ Notifier = (*Client)(nil)
@@ -0,0 +1,18 @@
+package gobark
+
+import (
+	"context"
+	"testing"
+)
+
+func TestClientImplementsNotifier(t *testing.T) {
+	client, transport := newRecordingClient(t)
+
+	var notifier Notifier = client
+	if err := notifier.Send(context.Background(), "test message"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if n := len(transport.Requests()); n != 1 {
+		t.Errorf("sent %d requests, want 1", n)
+	}
+}
@@ -0,0 +1,37 @@
+package gobark
+
+import (
+	"context"
+	"net/http"
+)
+
+// Observer receives callbacks at each phase of sending a notification.
+// It can be used to add logging, metrics or tracing to a client.
+// When failover servers are configured, the callbacks run once per server tried.
+type Observer interface {
+	// BeforeBuild is called before the request for a notification is built.
+	BeforeBuild(ctx context.Context)
+	// AfterBuild is called with the URL of the built request.
+	AfterBuild(ctx context.Context, url string)
+	// BeforeSend is called just before req is sent.
+	BeforeSend(ctx context.Context, req *http.Request)
+	// AfterSend is called once req has been sent.
+	// resp is nil if no response was received, and its body must not be read.
+	AfterSend(ctx context.Context, resp *http.Response, err error)
+}
+
+// NopObserver is an Observer that does nothing.
+// It can be embedded to implement only some of the Observer methods.
+type NopObserver struct{}
+
+// BeforeBuild implements Observer.
+func (NopObserver) BeforeBuild(ctx context.Context) {}
+
+// AfterBuild implements Observer.
+func (NopObserver) AfterBuild(ctx context.Context, url string) {}
+
+// BeforeSend implements Observer.
+func (NopObserver) BeforeSend(ctx context.Context, req *http.Request) {}
+
+// AfterSend implements Observer.
+func (NopObserver) AfterSend(ctx context.Context, resp *http.Response, err error) {}
@@ -0,0 +1,117 @@
+package gobark
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeObserver records the name of each callback in the order they are called.
+type fakeObserver struct {
+	calls []string
+	url   string
+	req   *http.Request
+	resp  *http.Response
+	err   error
+}
+
+func (o *fakeObserver) BeforeBuild(ctx context.Context) {
+	o.calls = append(o.calls, "BeforeBuild")
+}
+
+func (o *fakeObserver) AfterBuild(ctx context.Context, url string) {
+	o.calls = append(o.calls, "AfterBuild")
+	o.url = url
+}
+
+func (o *fakeObserver) BeforeSend(ctx context.Context, req *http.Request) {
+	o.calls = append(o.calls, "BeforeSend")
+	o.req = req
+}
+
+func (o *fakeObserver) AfterSend(ctx context.Context, resp *http.Response, err error) {
+	o.calls = append(o.calls, "AfterSend")
+	o.resp = resp
+	o.err = err
+}
+
+func TestWithObserver(t *testing.T) {
+	observer := &fakeObserver{}
+	client, _ := newRecordingClient(t, WithObserver(observer))
+
+	if err := client.Send(context.Background(), "test message"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	wantCalls := []string{"BeforeBuild", "AfterBuild", "BeforeSend", "AfterSend"}
+	if !reflect.DeepEqual(observer.calls, wantCalls) {
+		t.Errorf("calls = %v, want %v", observer.calls, wantCalls)
+	}
+	if !strings.HasPrefix(observer.url, "https://bark.example.com/test-key/") {
+		t.Errorf("url = %q, want the built notification URL", observer.url)
+	}
+	if observer.err != nil {
+		t.Errorf("AfterSend error = %v, want nil", observer.err)
+	}
+}
+
+func TestWithObserver_Nil(t *testing.T) {
+	if _, err := NewClient("", "test-key", WithObserver(nil)); err == nil {
+		t.Error("NewClient() error = nil, want error")
+	}
+}
+
+func TestWithRedactedLogging(t *testing.T) {
+	observer := &fakeObserver{}
+	client, transport := newRecordingClient(t, WithObserver(observer), WithRedactedLogging())
+
+	err := client.Send(context.Background(), "password is hunter2",
+		WithTitle("Credentials"), WithSubtitle("db01"), WithTimeSensitive())
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	wantURL := "https://bark.example.com/test-key/***/***/***?level=timeSensitive"
+	if observer.url != wantURL {
+		t.Errorf("observed url = %q, want %q", observer.url, wantURL)
+	}
+	if got := observer.req.URL.String(); got != wantURL {
+		t.Errorf("observed request url = %q, want %q", got, wantURL)
+	}
+	if got := observer.resp.Request.URL.String(); got != wantURL {
+		t.Errorf("observed response request url = %q, want %q", got, wantURL)
+	}
+	if sent := transport.Requests()[0]; sent.Body != "password is hunter2" || sent.Title != "Credentials" {
+		t.Errorf("sent notification = %q/%q, want it unredacted", sent.Title, sent.Body)
+	}
+}
+
+func TestWithRedactedLogging_TransportError(t *testing.T) {
+	// The server is down: start it and close it immediately, so the connection is refused
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+
+	observer := &fakeObserver{}
+	client, err := NewClient(server.URL, "test-key", WithObserver(observer), WithRedactedLogging(), WithSendHistory(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = client.Send(context.Background(), "password is hunter2", WithTitle("Credentials"))
+	if err == nil {
+		t.Fatal("Send() error = nil, want connection error")
+	}
+
+	errs := map[string]error{"Send": err, "AfterSend": observer.err, "RecentSends": client.RecentSends()[0].Err}
+	for name, err := range errs {
+		if msg := err.Error(); strings.Contains(msg, "hunter2") || strings.Contains(msg, "Credentials") {
+			t.Errorf("%s error = %q, want the notification content redacted", name, msg)
+		}
+		if !strings.Contains(err.Error(), "/test-key/***/***") {
+			t.Errorf("%s error = %q, want the redacted URL", name, err)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package gobark
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is the error of a send that panicked in one of the goroutines the
+// client starts, for SendAll, Enqueue or WithHedging, for example because of a
+// panicking observer or callback. The panic is recovered so that it does not
+// crash the program, and the send is not retried.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value interface{}
+	// Stack is the stack trace of the goroutine that panicked.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic during send: %v", e.Value)
+}
+
+// recoverPanic recovers from a panic and stores it in *err as a *PanicError.
+// It must be deferred directly.
+func recoverPanic(err *error) {
+	if r := recover(); r != nil {
+		*err = &permanentError{err: &PanicError{Value: r, Stack: debug.Stack()}}
+	}
+}
@@ -0,0 +1,109 @@
+package gobark
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// panickingObserver panics after every request is sent.
+type panickingObserver struct {
+	NopObserver
+}
+
+func (panickingObserver) AfterSend(ctx context.Context, resp *http.Response, err error) {
+	panic("observer failed")
+}
+
+// assertPanicError fails the test unless err is a recovered *PanicError with a stack trace.
+func assertPanicError(t *testing.T, err error) {
+	t.Helper()
+
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("error = %v, want a *PanicError", err)
+	}
+	if panicErr.Value != "observer failed" {
+		t.Errorf("Value = %v, want %q", panicErr.Value, "observer failed")
+	}
+	if !strings.Contains(string(panicErr.Stack), "AfterSend") {
+		t.Errorf("Stack = %s, want the stack of the panic", panicErr.Stack)
+	}
+}
+
+func TestPanicRecovery_SendAll(t *testing.T) {
+	client, _ := newRecordingClient(t, WithObserver(panickingObserver{}), WithRetry(2, time.Millisecond))
+
+	err := client.SendAll(context.Background(), []*Notification{NewNotification("disk full")})
+
+	var broadcastErr *BroadcastError
+	if !errors.As(err, &broadcastErr) || len(broadcastErr.Failures) != 1 {
+		t.Fatalf("SendAll() error = %v, want one failure", err)
+	}
+	assertPanicError(t, broadcastErr.Failures[0].Err)
+}
+
+func TestPanicRecovery_Queue(t *testing.T) {
+	var deadLetters []error
+	client, transport := newRecordingClient(t,
+		WithObserver(panickingObserver{}),
+		WithSendQueue(10, QueueBlock),
+		WithDeadLetter(func(ctx context.Context, body string, opts []Option, err error) {
+			deadLetters = append(deadLetters, err)
+		}),
+	)
+
+	for _, body := range []string{"first", "second"} {
+		if err := client.Enqueue(body); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+	client.Close()
+
+	// The worker keeps going after a panic
+	if n := len(transport.Requests()); n != 2 {
+		t.Errorf("sent %d requests, want 2", n)
+	}
+	if len(deadLetters) != 2 {
+		t.Fatalf("dead letters = %d, want 2", len(deadLetters))
+	}
+	assertPanicError(t, deadLetters[0])
+}
+
+func TestPanicRecovery_Hedging(t *testing.T) {
+	client, transport := newRecordingClient(t, WithObserver(panickingObserver{}), WithHedging(time.Hour), WithRetry(2, time.Millisecond))
+
+	err := client.Send(context.Background(), "disk full", WithID("disk"))
+	assertPanicError(t, err)
+
+	// Panics are not retried
+	if n := len(transport.Requests()); n != 1 {
+		t.Errorf("sent %d requests, want 1", n)
+	}
+}
+
+func TestPanicRecovery_QueueHedging(t *testing.T) {
+	var deadLetters []error
+	client, _ := newRecordingClient(t,
+		WithObserver(panickingObserver{}),
+		WithHedging(time.Hour),
+		WithSendQueue(10, QueueBlock),
+		WithDeadLetter(func(ctx context.Context, body string, opts []Option, err error) {
+			deadLetters = append(deadLetters, err)
+		}),
+	)
+
+	if err := client.Enqueue("disk full", WithID("disk")); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	client.Close()
+
+	// The panic recovered in the hedge goroutine is dead-lettered once
+	if len(deadLetters) != 1 {
+		t.Fatalf("dead letters = %d, want 1", len(deadLetters))
+	}
+	assertPanicError(t, deadLetters[0])
+}
@@ -0,0 +1,96 @@
+package gobark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// probeState records whether the startup probe has succeeded.
+type probeState struct {
+	mu     sync.Mutex
+	passed bool
+}
+
+// Ping checks that the server is reachable by requesting its /ping endpoint.
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.ping(ctx)
+	return err
+}
+
+// ping requests the /ping endpoint of the server and returns the response body.
+func (c *Client) ping(ctx context.Context) ([]byte, error) {
+	return c.pingServer(ctx, c.baseURL)
+}
+
+// pingServer requests the /ping endpoint of the server at baseURL and returns the response body.
+func (c *Client) pingServer(ctx context.Context, baseURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, joinURL(baseURL, "ping"), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if c.hostHeader != "" {
+		req.Host = c.hostHeader
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to ping server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to ping server: unexpected status code: %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ping response: %w", err)
+	}
+	return body, nil
+}
+
+// WithStartupProbe makes the client Ping the server before its first send, so
+// that an unreachable server fails the send straight away instead of going
+// through retries. With WithFailoverURLs or WithLoadBalance, the probe passes
+// if any of the servers answers. Once the probe succeeds, later sends skip it;
+// a failed probe fails the send and is tried again on the next one.
+func WithStartupProbe() ClientOption {
+	return func(c *Client) error {
+		c.probe = &probeState{}
+		return nil
+	}
+}
+
+// runProbe pings the servers unless the startup probe is disabled or has already succeeded.
+func (c *Client) runProbe(ctx context.Context) error {
+	if c.probe == nil {
+		return nil
+	}
+
+	c.probe.mu.Lock()
+	defer c.probe.mu.Unlock()
+
+	if c.probe.passed {
+		return nil
+	}
+	var errs []error
+	for _, baseURL := range c.baseURLs() {
+		if _, err := c.pingServer(ctx, baseURL); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		c.probe.passed = true
+		return nil
+	}
+	return fmt.Errorf("startup probe failed: %w", errors.Join(errs...))
+}
+
+// reset makes the startup probe run again before the next send.
+func (p *probeState) reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.passed = false
+}
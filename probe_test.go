@@ -0,0 +1,137 @@
+package gobark
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newProbeServer starts a server that answers /ping with the status returned
+// by pingStatus, counting the pings and the notifications it receives.
+func newProbeServer(t *testing.T, pingStatus func() int) (*httptest.Server, *int, *int) {
+	t.Helper()
+
+	var pings, sends int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ping" {
+			pings++
+			w.WriteHeader(pingStatus())
+			return
+		}
+		sends++
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &pings, &sends
+}
+
+func TestWithStartupProbe(t *testing.T) {
+	server, pings, sends := newProbeServer(t, func() int { return http.StatusOK })
+
+	client, err := NewClient(server.URL, "test-key", WithStartupProbe())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if err := client.Send(ctx, "test message"); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	if *pings != 1 {
+		t.Errorf("pinged %d times, want 1", *pings)
+	}
+	if *sends != 3 {
+		t.Errorf("sent %d notifications, want 3", *sends)
+	}
+}
+
+func TestWithStartupProbe_Failure(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	server, pings, sends := newProbeServer(t, func() int { return status })
+
+	client, err := NewClient(server.URL, "test-key", WithStartupProbe(), WithRetry(3, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	err = client.Send(ctx, "test message")
+	if err == nil || !strings.Contains(err.Error(), "startup probe failed") {
+		t.Fatalf("Send() error = %v, want the startup probe to fail", err)
+	}
+	if *sends != 0 {
+		t.Errorf("sent %d notifications, want the failed probe to block the send", *sends)
+	}
+
+	// A failed probe is not cached, so the next send probes again
+	status = http.StatusOK
+	if err := client.Send(ctx, "test message"); err != nil {
+		t.Fatalf("Send() after recovery error = %v", err)
+	}
+	if *pings != 2 {
+		t.Errorf("pinged %d times, want 2", *pings)
+	}
+	if *sends != 1 {
+		t.Errorf("sent %d notifications, want 1", *sends)
+	}
+}
+
+func TestWithStartupProbe_Failover(t *testing.T) {
+	primary, primaryPings, _ := newProbeServer(t, func() int { return http.StatusServiceUnavailable })
+	primary.Close()
+	backup, backupPings, backupSends := newProbeServer(t, func() int { return http.StatusOK })
+
+	client, err := NewClient(primary.URL, "test-key", WithStartupProbe(), WithFailoverURLs(backup.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Send(context.Background(), "test message"); err != nil {
+		t.Fatalf("Send() error = %v, want the probe to pass through the backup", err)
+	}
+	if *primaryPings != 0 || *backupPings != 1 {
+		t.Errorf("pinged primary %d and backup %d times, want 0 and 1", *primaryPings, *backupPings)
+	}
+	if *backupSends != 1 {
+		t.Errorf("sent %d notifications to the backup, want 1", *backupSends)
+	}
+}
+
+func TestWithStartupProbe_SendToKeys(t *testing.T) {
+	server, pings, sends := newProbeServer(t, func() int { return http.StatusServiceUnavailable })
+
+	client, err := NewClient(server.URL, "test-key", WithStartupProbe())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = client.SendToKeys(context.Background(), []string{"key-1", "key-2"}, "test message")
+	if err == nil || !strings.Contains(err.Error(), "startup probe failed") {
+		t.Fatalf("SendToKeys() error = %v, want the startup probe to fail", err)
+	}
+	if *pings != 1 || *sends != 0 {
+		t.Errorf("pinged %d times and sent %d batches, want 1 and 0", *pings, *sends)
+	}
+}
+
+func TestPing(t *testing.T) {
+	server, pings, _ := newProbeServer(t, func() int { return http.StatusOK })
+
+	client, err := NewClient(server.URL, "test-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+	if *pings != 1 {
+		t.Errorf("pinged %d times, want 1", *pings)
+	}
+}
@@ -0,0 +1,183 @@
+package gobark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrQueueClosed is returned by Enqueue once the client has been closed.
+var ErrQueueClosed = errors.New("send queue closed")
+
+// QueuePolicy decides what Enqueue does when the send queue is full.
+type QueuePolicy int
+
+const (
+	// QueueBlock makes Enqueue wait until the queue has room.
+	QueueBlock QueuePolicy = iota
+	// QueueDropOldest makes Enqueue discard the oldest queued notification to make room.
+	QueueDropOldest
+)
+
+// WithSendQueue enables Enqueue, which queues notifications for a background
+// worker that sends them one at a time, in order, through the client's rate
+// limits and retries. At most size notifications are queued; policy decides
+// what happens when the queue is full. The worker starts on the first Enqueue;
+// call Close to send the queued notifications and stop it.
+func WithSendQueue(size int, policy QueuePolicy) ClientOption {
+	return func(c *Client) error {
+		if size <= 0 {
+			return fmt.Errorf("queue size must be positive")
+		}
+		if policy != QueueBlock && policy != QueueDropOldest {
+			return fmt.Errorf("invalid queue policy %d", policy)
+		}
+		c.queue = newSendQueue(size, policy)
+		return nil
+	}
+}
+
+// queuedSend is a notification waiting in the send queue.
+type queuedSend struct {
+	n    *Notification
+	body string
+	opts []Option
+}
+
+// sendQueue holds the notifications waiting for the queue worker.
+type sendQueue struct {
+	mu      sync.Mutex
+	changed *sync.Cond
+	items   []queuedSend
+	size    int
+	policy  QueuePolicy
+	started bool
+	closed  bool
+	done    chan struct{}
+}
+
+func newSendQueue(size int, policy QueuePolicy) *sendQueue {
+	q := &sendQueue{size: size, policy: policy, done: make(chan struct{})}
+	q.changed = sync.NewCond(&q.mu)
+	return q
+}
+
+// Enqueue queues a notification to be sent in the background and returns without
+// waiting for it to be sent, except under QueueBlock while the queue is full.
+// Invalid notifications are rejected straight away; notifications that cannot be
+// delivered are passed to the dead letter handler, if any.
+// It requires WithSendQueue, and returns ErrQueueClosed once Close has been called.
+func (c *Client) Enqueue(body string, opts ...Option) error {
+	if c.queue == nil {
+		return fmt.Errorf("send queue not enabled, see WithSendQueue")
+	}
+
+	n, err := c.newNotification(body, opts)
+	if err != nil {
+		return err
+	}
+
+	return c.queue.push(queuedSend{n: n, body: body, opts: opts}, c.runQueue)
+}
+
+// Close sends the notifications left in the send queue, then stops its worker.
+// Later calls to Enqueue return ErrQueueClosed. Close does nothing without WithSendQueue.
+func (c *Client) Close() error {
+	if c.queue == nil {
+		return nil
+	}
+
+	q := c.queue
+	q.mu.Lock()
+	q.closed = true
+	started := q.started
+	q.changed.Broadcast()
+	q.mu.Unlock()
+
+	if started {
+		<-q.done
+	}
+	return nil
+}
+
+// push adds item to the queue, starting worker on the first push.
+func (q *sendQueue) push(item queuedSend, worker func()) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for !q.closed && len(q.items) >= q.size {
+		if q.policy == QueueDropOldest {
+			q.items = q.items[1:]
+			break
+		}
+		q.changed.Wait()
+	}
+	if q.closed {
+		return ErrQueueClosed
+	}
+
+	if !q.started {
+		q.started = true
+		go worker()
+	}
+	q.items = append(q.items, item)
+	q.changed.Broadcast()
+	return nil
+}
+
+// pop removes the oldest item from the queue, waiting for one if it is empty.
+// It returns false once the queue is closed and empty.
+func (q *sendQueue) pop() (queuedSend, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.items) == 0 && !q.closed {
+		q.changed.Wait()
+	}
+	if len(q.items) == 0 {
+		return queuedSend{}, false
+	}
+
+	item := q.items[0]
+	q.items = q.items[1:]
+	q.changed.Broadcast()
+	return item, true
+}
+
+// runQueue sends the queued notifications in order until the queue is closed and empty.
+func (c *Client) runQueue() {
+	defer close(c.queue.done)
+
+	for {
+		item, ok := c.queue.pop()
+		if !ok {
+			return
+		}
+		c.sendQueued(item)
+	}
+}
+
+// sendQueued delivers a queued notification. A panic while sending is recovered
+// and passed to the dead letter handler, if any, so that it does not stop the worker.
+// Panics recovered further down, as in hedged sends, are already errors that
+// deliverWithDeadLetter passed to the handler.
+func (c *Client) sendQueued(item queuedSend) {
+	ctx := context.Background()
+
+	var (
+		err      error
+		returned bool
+	)
+	func() {
+		defer recoverPanic(&err)
+		_, err = c.deliverWithDeadLetter(ctx, item.n, item.body, item.opts)
+		returned = true
+	}()
+
+	if !returned && c.deadLetter != nil {
+		// A dead letter handler that panics again is given up on
+		defer recoverPanic(&err)
+		c.deadLetter(ctx, item.body, item.opts, err)
+	}
+}
@@ -0,0 +1,128 @@
+package gobark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/xpzouying/gobark/barktest"
+)
+
+func TestWithSendQueue(t *testing.T) {
+	client, transport := newRecordingClient(t, WithSendQueue(10, QueueBlock))
+
+	var want []string
+	for i := 0; i < 100; i++ {
+		body := fmt.Sprintf("message %d", i)
+		want = append(want, body)
+		if err := client.Enqueue(body); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	var got []string
+	for _, req := range transport.Requests() {
+		got = append(got, req.Body)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sent bodies = %q, want all %d in order", got, len(want))
+	}
+
+	if err := client.Enqueue("too late"); !errors.Is(err, ErrQueueClosed) {
+		t.Errorf("Enqueue() after Close error = %v, want %v", err, ErrQueueClosed)
+	}
+}
+
+// gatedTransport records requests like barktest.RecordingTransport, but holds
+// the first request until release is closed, signalling started once it arrives.
+type gatedTransport struct {
+	barktest.RecordingTransport
+	started, release chan struct{}
+	held             bool
+}
+
+func (t *gatedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.held {
+		t.held = true
+		close(t.started)
+		<-t.release
+	}
+	return t.RecordingTransport.RoundTrip(req)
+}
+
+func TestWithSendQueue_DropOldest(t *testing.T) {
+	transport := &gatedTransport{started: make(chan struct{}), release: make(chan struct{})}
+	client, err := NewClient("https://bark.example.com", "test-key",
+		WithHTTPClient(&http.Client{Transport: transport}),
+		WithSendQueue(2, QueueDropOldest),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The worker holds message 0 in flight while the others fill the queue
+	if err := client.Enqueue("message 0"); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	<-transport.started
+	for i := 1; i <= 5; i++ {
+		if err := client.Enqueue(fmt.Sprintf("message %d", i)); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+	close(transport.release)
+	client.Close()
+
+	var got []string
+	for _, req := range transport.Requests() {
+		got = append(got, req.Body)
+	}
+	want := []string{"message 0", "message 4", "message 5"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sent bodies = %q, want %q", got, want)
+	}
+}
+
+func TestWithSendQueue_DeadLetter(t *testing.T) {
+	var failed []string
+	client, _ := newRecordingClient(t,
+		WithSendQueue(10, QueueBlock),
+		WithDeadLetter(func(ctx context.Context, body string, opts []Option, err error) { failed = append(failed, body) }),
+		WithSendInterceptor(func(ctx context.Context, req *http.Request) error { return errors.New("rejected") }),
+	)
+
+	if err := client.Enqueue("test message"); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	client.Close()
+
+	if !reflect.DeepEqual(failed, []string{"test message"}) {
+		t.Errorf("dead letters = %q, want the failed notification", failed)
+	}
+}
+
+func TestEnqueue_Invalid(t *testing.T) {
+	client, _ := newRecordingClient(t, WithSendQueue(10, QueueBlock))
+	defer client.Close()
+
+	if err := client.Enqueue(""); err == nil {
+		t.Error("Enqueue() with empty body error = nil, want error")
+	}
+}
+
+func TestEnqueue_WithoutQueue(t *testing.T) {
+	client, _ := newRecordingClient(t)
+
+	if err := client.Enqueue("test message"); err == nil {
+		t.Error("Enqueue() without WithSendQueue error = nil, want error")
+	}
+	if err := client.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+}
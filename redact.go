@@ -0,0 +1,101 @@
+package gobark
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// redactedValue replaces the notification content in redacted URLs.
+const redactedValue = "***"
+
+// WithRedactedLogging hides the notification content from observers: the URLs
+// and requests they receive have the title, subtitle and body in the URL path
+// replaced with "***", and requests carry no payload. The URLs in the errors of
+// requests that get no response are redacted the same way, since these errors
+// also reach send history and callers. The server, key and parameters such as
+// the level are left visible. Requests sent to the server are not affected.
+func WithRedactedLogging() ClientOption {
+	return func(c *Client) error {
+		c.redactLogging = true
+		return nil
+	}
+}
+
+// observedURL returns the URL of req as passed to observers.
+// POST requests carry the notification in their payload, so their URL is never redacted.
+func (c *Client) observedURL(req *http.Request) string {
+	if !c.redactLogging || req.Method == http.MethodPost {
+		return req.URL.String()
+	}
+	return c.redactURL(req.URL.String())
+}
+
+// observedRequest returns req as passed to observers.
+func (c *Client) observedRequest(req *http.Request) *http.Request {
+	if !c.redactLogging {
+		return req
+	}
+
+	redacted := req.Clone(req.Context())
+	redacted.Body = http.NoBody
+	redacted.GetBody = nil
+	if u, err := req.URL.Parse(c.observedURL(req)); err == nil {
+		redacted.URL = u
+	}
+	return redacted
+}
+
+// observedResponse returns resp as passed to observers, with its Request
+// replaced by observed, the request as passed to observers.
+func (c *Client) observedResponse(resp *http.Response, observed *http.Request) *http.Response {
+	if !c.redactLogging {
+		return resp
+	}
+
+	redacted := *resp
+	redacted.Request = observed
+	return &redacted
+}
+
+// redactError replaces the URL of the *url.Error in err, as returned by the
+// HTTP client for req, with the URL passed to observers.
+func (c *Client) redactError(err error, req *http.Request) error {
+	var urlErr *url.Error
+	if c.redactLogging && errors.As(err, &urlErr) {
+		urlErr.URL = c.observedURL(req)
+	}
+	return err
+}
+
+// redactURL replaces every path segment following the key in rawURL with "***",
+// or every segment if the key is sent in a header.
+// The segments are found relative to whichever configured server rawURL belongs to.
+func (c *Client) redactURL(rawURL string) string {
+	servers := append(append([]string{c.baseURL}, c.balancedURLs...), c.failoverURLs...)
+	for _, server := range servers {
+		prefix := joinURL(server, "")
+		if !strings.HasPrefix(rawURL, prefix) {
+			continue
+		}
+
+		path, query, hasQuery := strings.Cut(rawURL[len(prefix):], "?")
+		segments := strings.Split(path, "/")
+		first := 1
+		if c.keyHeader != "" {
+			first = 0
+		}
+		for i := first; i < len(segments); i++ {
+			segments[i] = redactedValue
+		}
+
+		redacted := prefix + strings.Join(segments, "/")
+		if hasQuery {
+			redacted += "?" + query
+		}
+		return redacted
+	}
+
+	return rawURL
+}
@@ -0,0 +1,79 @@
+package gobark
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// maxRedirects is the number of redirects followed before giving up, as in http.Client.
+const maxRedirects = 10
+
+// WithFollowRedirects sets whether redirect responses from the server are followed.
+// When following, POST requests are resent as POST with the same payload, even after
+// a 301 or 302 that a plain http.Client would turn into a GET, and redirects from
+// https to http are refused so that keys are never sent in the clear.
+// When not following, a redirect fails the send with its status code.
+// Redirects are followed this way by default, unless the client passed to
+// WithHTTPClient sets its own CheckRedirect and this option is not used.
+func WithFollowRedirects(follow bool) ClientOption {
+	return func(c *Client) error {
+		c.followRedirects = &follow
+		return nil
+	}
+}
+
+// configureRedirects installs the redirect policy on the HTTP client.
+// The client is copied so that a client passed to WithHTTPClient is not modified.
+func (c *Client) configureRedirects() {
+	if c.followRedirects == nil && c.client.CheckRedirect != nil {
+		return
+	}
+
+	checkRedirect := checkRedirect
+	if c.followRedirects != nil && !*c.followRedirects {
+		checkRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+
+	httpClient := *c.client
+	httpClient.CheckRedirect = checkRedirect
+	c.client = &httpClient
+}
+
+// checkRedirect follows a redirect, keeping the method and payload of the original
+// request, unless it downgrades from https to http.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return errors.New("stopped after 10 redirects")
+	}
+
+	orig := via[0]
+	if orig.URL.Scheme == "https" && req.URL.Scheme != "https" {
+		return fmt.Errorf("refusing redirect from https to %s", req.URL.Scheme)
+	}
+
+	if req.Method != orig.Method {
+		if orig.GetBody == nil && orig.Body != nil && orig.Body != http.NoBody {
+			return fmt.Errorf("cannot resend %s request body after redirect", orig.Method)
+		}
+		req.Method = orig.Method
+		if orig.GetBody != nil {
+			body, err := orig.GetBody()
+			if err != nil {
+				return fmt.Errorf("failed to rewind request body: %w", err)
+			}
+			req.Body = body
+			req.GetBody = orig.GetBody
+			req.ContentLength = orig.ContentLength
+		}
+		for _, name := range []string{"Content-Type", "Content-Encoding"} {
+			if value := orig.Header.Get(name); value != "" {
+				req.Header.Set(name, value)
+			}
+		}
+	}
+
+	return nil
+}
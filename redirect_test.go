@@ -0,0 +1,128 @@
+package gobark
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newRedirectServer returns a server that redirects every request under /old/ to
+// the same path under /new/ with status, and records the requests received under /new/.
+func newRedirectServer(t *testing.T, status int) (*httptest.Server, *[]*http.Request, *[]string) {
+	t.Helper()
+
+	var (
+		requests []*http.Request
+		bodies   []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/old/") {
+			http.Redirect(w, r, "/new/"+strings.TrimPrefix(r.URL.Path, "/old/"), status)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		requests = append(requests, r)
+		bodies = append(bodies, string(body))
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &requests, &bodies
+}
+
+func TestRedirect_GET(t *testing.T) {
+	server, requests, _ := newRedirectServer(t, http.StatusMovedPermanently)
+
+	client, err := NewClient(server.URL+"/old", "test-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Send(context.Background(), "test message"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if len(*requests) != 1 {
+		t.Fatalf("server received %d redirected requests, want 1", len(*requests))
+	}
+	if req := (*requests)[0]; req.Method != http.MethodGet || req.URL.Path != "/new/test-key/"+defaultTitle+"/test message" {
+		t.Errorf("redirected request = %s %s, want GET to the new path", req.Method, req.URL.Path)
+	}
+}
+
+func TestRedirect_POSTKeepsMethodAndPayload(t *testing.T) {
+	server, requests, bodies := newRedirectServer(t, http.StatusFound)
+
+	client, err := NewClient(server.URL+"/old", "test-key", WithPostMode())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Send(context.Background(), "test message"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if len(*requests) != 1 {
+		t.Fatalf("server received %d redirected requests, want 1", len(*requests))
+	}
+	req := (*requests)[0]
+	if req.Method != http.MethodPost || req.URL.Path != "/new/push" {
+		t.Errorf("redirected request = %s %s, want POST /new/push", req.Method, req.URL.Path)
+	}
+	if got := req.Header.Get("Content-Type"); got != "application/json; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want JSON", got)
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal([]byte((*bodies)[0]), &payload); err != nil {
+		t.Fatalf("redirected payload is not JSON: %v", err)
+	}
+	if payload["body"] != "test message" || payload["device_key"] != "test-key" {
+		t.Errorf("redirected payload = %v, want the original notification", payload)
+	}
+}
+
+func TestWithFollowRedirects_Disabled(t *testing.T) {
+	server, requests, _ := newRedirectServer(t, http.StatusFound)
+
+	client, err := NewClient(server.URL+"/old", "test-key", WithFollowRedirects(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = client.Send(context.Background(), "test message")
+	if err == nil || err.Error() != "unexpected status code: 302" {
+		t.Errorf("Send() error = %v, want unexpected status code: 302", err)
+	}
+	if len(*requests) != 0 {
+		t.Errorf("server received %d redirected requests, want 0", len(*requests))
+	}
+}
+
+func TestRedirect_RefusesHTTPSDowngrade(t *testing.T) {
+	plain, requests, _ := newRedirectServer(t, http.StatusFound)
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, plain.URL+"/new"+r.URL.Path, http.StatusFound)
+	}))
+	defer secure.Close()
+
+	client, err := NewClient(secure.URL, "test-key", WithHTTPClient(secure.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = client.Send(context.Background(), "test message")
+	if err == nil || !strings.Contains(err.Error(), "refusing redirect from https to http") {
+		t.Errorf("Send() error = %v, want refused downgrade", err)
+	}
+	if len(*requests) != 0 {
+		t.Errorf("plain server received %d requests, want 0", len(*requests))
+	}
+}
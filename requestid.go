@@ -0,0 +1,56 @@
+package gobark
+
+import (
+	"context"
+	"fmt"
+)
+
+// RequestIDHeader is the header carrying the request ID set by WithRequestIDGenerator.
+const RequestIDHeader = "X-Request-Id"
+
+// WithRequestIDGenerator tags every send with an ID returned by gen, such as a
+// UUID, to correlate it across logs. The ID is sent in the X-Request-Id header
+// and is available to observers and send interceptors through RequestIDFromContext.
+// Retries and failover requests of a send share its ID.
+func WithRequestIDGenerator(gen func() string) ClientOption {
+	return func(c *Client) error {
+		if gen == nil {
+			return fmt.Errorf("request id generator must not be nil")
+		}
+		c.requestIDGenerator = gen
+		return nil
+	}
+}
+
+// WithRequestIDAsNotificationID also uses the request ID set by
+// WithRequestIDGenerator as the Bark ID of notifications without one set with WithID.
+func WithRequestIDAsNotificationID() ClientOption {
+	return func(c *Client) error {
+		c.requestIDAsID = true
+		return nil
+	}
+}
+
+// requestIDKey is the context key of the request ID of a send.
+type requestIDKey struct{}
+
+// RequestIDFromContext returns the request ID of the send that ctx belongs to,
+// as passed to observers and send interceptors, if WithRequestIDGenerator is set.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok
+}
+
+// withRequestID returns a context carrying a new request ID for the send of n,
+// and sets it as the ID of n if configured. Without a generator, ctx is returned unchanged.
+func (c *Client) withRequestID(ctx context.Context, n *Notification) context.Context {
+	if c.requestIDGenerator == nil {
+		return ctx
+	}
+
+	id := c.requestIDGenerator()
+	if c.requestIDAsID && n.id == "" {
+		n.id = id
+	}
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
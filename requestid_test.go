@@ -0,0 +1,85 @@
+package gobark
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// requestIDObserver records the request ID seen by BeforeSend.
+type requestIDObserver struct {
+	NopObserver
+	ids []string
+}
+
+func (o *requestIDObserver) BeforeSend(ctx context.Context, req *http.Request) {
+	id, _ := RequestIDFromContext(ctx)
+	o.ids = append(o.ids, id)
+}
+
+// sequentialIDs returns a generator of the IDs req-1, req-2 and so on.
+func sequentialIDs() func() string {
+	var next int
+	return func() string {
+		next++
+		return fmt.Sprintf("req-%d", next)
+	}
+}
+
+func TestWithRequestIDGenerator(t *testing.T) {
+	observer := &requestIDObserver{}
+	client, transport := newRecordingClient(t, WithRequestIDGenerator(sequentialIDs()), WithObserver(observer))
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if err := client.Send(ctx, "test message"); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	var headers []string
+	for _, req := range transport.Requests() {
+		headers = append(headers, req.Header.Get(RequestIDHeader))
+		if req.Params["id"] != "" {
+			t.Errorf("id = %q, want no notification id", req.Params["id"])
+		}
+	}
+	want := []string{"req-1", "req-2"}
+	if !reflect.DeepEqual(headers, want) {
+		t.Errorf("request id headers = %q, want %q", headers, want)
+	}
+	if !reflect.DeepEqual(observer.ids, want) {
+		t.Errorf("observed request ids = %q, want %q", observer.ids, want)
+	}
+}
+
+func TestWithRequestIDAsNotificationID(t *testing.T) {
+	client, transport := newRecordingClient(t, WithRequestIDGenerator(sequentialIDs()), WithRequestIDAsNotificationID())
+
+	ctx := context.Background()
+	if err := client.Send(ctx, "test message"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if err := client.Send(ctx, "test message", WithID("deploy-42")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	requests := transport.Requests()
+	if got := requests[0].Params["id"]; got != "req-1" {
+		t.Errorf("id = %q, want the request id %q", got, "req-1")
+	}
+	if got := requests[1].Params["id"]; got != "deploy-42" {
+		t.Errorf("id = %q, want the explicit id %q", got, "deploy-42")
+	}
+	if got := requests[1].Header.Get(RequestIDHeader); got != "req-2" {
+		t.Errorf("request id header = %q, want %q", got, "req-2")
+	}
+}
+
+func TestWithRequestIDGenerator_Nil(t *testing.T) {
+	if _, err := NewClient("", "test-key", WithRequestIDGenerator(nil)); err == nil {
+		t.Error("NewClient() error = nil, want error")
+	}
+}
@@ -0,0 +1,49 @@
+package gobark
+
+import (
+	"golang.org/x/time/rate"
+)
+
+// Reset clears the state the client accumulates while sending, as if it had
+// just been created, while keeping its options:
+//   - the notifications remembered for WithDedupWindow are forgotten,
+//   - WithLoadBalance starts again from the base URL,
+//   - the IDs tracked by WithActiveIDTracking are forgotten, without clearing
+//     the notifications from the device,
+//   - the sounds reported by the server are fetched again,
+//   - the WithGroupRateLimit limiters are refilled,
+//   - the WithStartupProbe probe runs again before the next send,
+//   - the requests kept by WithSendHistory are forgotten,
+//   - the server clock offset measured by ServerTime is forgotten, so that
+//     WithServerTimeStamp uses the local time until ServerTime is called again.
+//
+// Reset must not be called concurrently with sends.
+func (c *Client) Reset() {
+	if c.dedup != nil {
+		c.dedup.reset()
+	}
+
+	c.balanceNext.Store(0)
+
+	if c.activeIDs != nil {
+		c.activeIDs.reset()
+	}
+
+	c.soundCache.mu.Lock()
+	c.soundCache.sounds, c.soundCache.fetched = nil, false
+	c.soundCache.mu.Unlock()
+
+	for group, limiter := range c.groupLimiters {
+		c.groupLimiters[group] = rate.NewLimiter(limiter.Limit(), limiter.Burst())
+	}
+
+	if c.probe != nil {
+		c.probe.reset()
+	}
+
+	if c.history != nil {
+		c.history.reset()
+	}
+
+	c.serverClockOffset.Store(0)
+}
@@ -0,0 +1,69 @@
+package gobark
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReset(t *testing.T) {
+	client, transport := newRecordingClient(t,
+		WithDedupWindow(time.Hour),
+		WithLoadBalance([]string{"https://bark2.example.com"}),
+	)
+
+	ctx := context.Background()
+	if err := client.Send(ctx, "disk full"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if err := client.Send(ctx, "disk full"); !errors.Is(err, ErrDuplicateSuppressed) {
+		t.Fatalf("Send() of duplicate error = %v, want %v", err, ErrDuplicateSuppressed)
+	}
+
+	client.Reset()
+
+	if err := client.Send(ctx, "disk full"); err != nil {
+		t.Fatalf("Send() after Reset error = %v, want the dedup cache cleared", err)
+	}
+
+	requests := transport.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("sent %d requests, want 2", len(requests))
+	}
+	// Both sends went to the first node, since Reset restarts the rotation
+	for i, req := range requests {
+		if !strings.HasPrefix(req.URL, "https://bark.example.com/") {
+			t.Errorf("request %d url = %q, want the first node", i, req.URL)
+		}
+	}
+}
+
+func TestReset_HistoryAndServerTime(t *testing.T) {
+	client, transport := newRecordingClient(t, WithSendHistory(10), WithServerTimeStamp("2006"))
+	transport.ResponseBody = `{"code":200,"message":"pong","timestamp":4102444800}`
+
+	ctx := context.Background()
+	if _, err := client.ServerTime(ctx); err != nil {
+		t.Fatalf("ServerTime() error = %v", err)
+	}
+	if err := client.Send(ctx, "disk full"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if got := transport.Requests()[1].Body; got != "2100 disk full" {
+		t.Fatalf("body = %q, want the server year", got)
+	}
+
+	client.Reset()
+
+	if got := client.RecentSends(); len(got) != 0 {
+		t.Errorf("RecentSends() after Reset = %v, want none", got)
+	}
+	if err := client.Send(ctx, "disk full"); err != nil {
+		t.Fatalf("Send() after Reset error = %v", err)
+	}
+	if got, want := transport.Requests()[2].Body, time.Now().Format("2006")+" disk full"; got != want {
+		t.Errorf("body after Reset = %q, want %q", got, want)
+	}
+}
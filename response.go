@@ -0,0 +1,93 @@
+package gobark
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// Response is the body of a Bark server response to a notification.
+type Response struct {
+	// Code is the result code reported by the server, 200 on success.
+	Code int `json:"code"`
+	// Message describes the result, such as "success".
+	Message string `json:"message"`
+	// Timestamp is the server time of the response, in Unix seconds.
+	Timestamp int64 `json:"timestamp"`
+}
+
+// WithResponseDecoder sets how SendWithResponse decodes the body of server responses,
+// for servers that do not reply with Bark's JSON format. The default decodes JSON.
+func WithResponseDecoder(decode func(body []byte) (*Response, error)) ClientOption {
+	return func(c *Client) error {
+		if decode == nil {
+			return fmt.Errorf("response decoder must not be nil")
+		}
+		c.decodeResponse = decode
+		return nil
+	}
+}
+
+// WithSuccessBodyCodes sets the codes that count as success in the code field
+// of response bodies, for forks that do not report success with code 200.
+// Responses with status 200 and any other body code make Send and
+// SendWithResponse fail, without being retried. By default the body code is
+// not checked. It requires reading every response body, decoded as set by
+// WithResponseDecoder.
+func WithSuccessBodyCodes(codes ...int) ClientOption {
+	return func(c *Client) error {
+		if len(codes) == 0 {
+			return fmt.Errorf("at least one success body code is required")
+		}
+		success := make(map[int]bool, len(codes))
+		for _, code := range codes {
+			success[code] = true
+		}
+		c.successBodyCodes = success
+		return nil
+	}
+}
+
+// decodeJSONResponse decodes a response body in Bark's JSON format.
+func decodeJSONResponse(body []byte) (*Response, error) {
+	var resp Response
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SendWithResponse sends a push notification like Send and also returns the decoded
+// body of the last response received from the server, even if the send failed.
+// The response is nil if no response was received.
+func (c *Client) SendWithResponse(ctx context.Context, body string, opts ...Option) (*Response, error) {
+	var (
+		mu       sync.Mutex
+		received bool
+		last     []byte
+	)
+	ctx = withResponseSink(ctx, func(status int, body []byte) {
+		mu.Lock()
+		defer mu.Unlock()
+		received, last = true, body
+	})
+
+	_, err := c.SendStatus(ctx, body, opts...)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !received {
+		return nil, err
+	}
+
+	resp, decodeErr := c.decodeResponse(last)
+	if decodeErr != nil {
+		decodeErr = fmt.Errorf("failed to decode response: %w", decodeErr)
+		if err == nil {
+			return nil, decodeErr
+		}
+		return nil, err
+	}
+	return resp, err
+}
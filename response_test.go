@@ -0,0 +1,112 @@
+package gobark
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSendWithResponse(t *testing.T) {
+	client, transport := newRecordingClient(t)
+	transport.ResponseBody = `{"code":200,"message":"success","timestamp":1700000000}`
+
+	resp, err := client.SendWithResponse(context.Background(), "test message")
+	if err != nil {
+		t.Fatalf("SendWithResponse() error = %v", err)
+	}
+
+	want := Response{Code: 200, Message: "success", Timestamp: 1700000000}
+	if *resp != want {
+		t.Errorf("response = %+v, want %+v", *resp, want)
+	}
+}
+
+func TestSendWithResponse_FailedSend(t *testing.T) {
+	client, transport := newRecordingClient(t)
+	transport.StatusCode = http.StatusBadRequest
+	transport.ResponseBody = `{"code":400,"message":"failed to get device token"}`
+
+	resp, err := client.SendWithResponse(context.Background(), "test message")
+	if err == nil {
+		t.Fatal("SendWithResponse() error = nil, want error")
+	}
+	if resp == nil || resp.Code != 400 || resp.Message != "failed to get device token" {
+		t.Errorf("response = %+v, want the server's error response", resp)
+	}
+}
+
+func TestWithResponseDecoder(t *testing.T) {
+	// decodeXML parses responses of the form <result code="..">message</result>
+	decodeXML := func(body []byte) (*Response, error) {
+		var resp Response
+		text := strings.TrimSuffix(string(body), "</result>")
+		if _, err := fmt.Sscanf(text, `<result code="%d">`, &resp.Code); err != nil {
+			return nil, err
+		}
+		resp.Message = text[strings.Index(text, ">")+1:]
+		return &resp, nil
+	}
+
+	client, transport := newRecordingClient(t, WithResponseDecoder(decodeXML))
+	transport.ResponseBody = `<result code="200">queued</result>`
+
+	resp, err := client.SendWithResponse(context.Background(), "test message")
+	if err != nil {
+		t.Fatalf("SendWithResponse() error = %v", err)
+	}
+	if resp.Code != 200 || resp.Message != "queued" {
+		t.Errorf("response = %+v, want code 200 and message queued", resp)
+	}
+}
+
+func TestSendWithResponse_UndecodableBody(t *testing.T) {
+	client, transport := newRecordingClient(t)
+	transport.ResponseBody = `<html>OK</html>`
+
+	if _, err := client.SendWithResponse(context.Background(), "test message"); err == nil {
+		t.Error("SendWithResponse() error = nil, want decode error")
+	}
+}
+
+func TestWithSuccessBodyCodes(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr bool
+	}{
+		{name: "success code", body: `{"code":0,"message":"ok"}`},
+		{name: "other code", body: `{"code":200,"message":"success"}`, wantErr: true},
+		{name: "error code", body: `{"code":1,"message":"invalid device"}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, transport := newRecordingClient(t, WithSuccessBodyCodes(0), WithRetry(2, time.Millisecond))
+			transport.ResponseBody = tt.body
+
+			if err := client.Send(context.Background(), "test message"); (err != nil) != tt.wantErr {
+				t.Errorf("Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			resp, err := client.SendWithResponse(context.Background(), "test message")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SendWithResponse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if resp == nil {
+				t.Fatal("SendWithResponse() response = nil, want the server response")
+			}
+			// Body codes outside the success codes are not retried
+			if n := len(transport.Requests()); n != 2 {
+				t.Errorf("sent %d requests, want 2", n)
+			}
+		})
+	}
+}
+
+func TestWithSuccessBodyCodes_Empty(t *testing.T) {
+	if _, err := NewClient("", "test-key", WithSuccessBodyCodes()); err == nil {
+		t.Error("NewClient() error = nil, want error")
+	}
+}
@@ -0,0 +1,422 @@
+package gobark
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// maxRetryBackoff caps the wait between two retries.
+const maxRetryBackoff = 30 * time.Second
+
+// defaultMaxRetryAfter is the longest Retry-After delay waited for by sends
+// without a deadline, unless configured with WithMaxRetryAfter.
+const defaultMaxRetryAfter = 5 * time.Minute
+
+// WithRetry retries failed sends up to maxRetries times. The wait between attempts
+// starts at initialBackoff and doubles after each retry.
+// Transport errors and the statuses set by WithRetryableStatuses are retried;
+// by default these are 429 Too Many Requests and any 5xx status.
+// When a retried response carries a Retry-After header, either in seconds or as an
+// HTTP date, the delay it requests is waited instead of the backoff. A delay
+// ending past the deadline of the send, set by its context or WithRetryDeadline,
+// ends the retries instead. Sends without a deadline wait at most 5 minutes,
+// see WithMaxRetryAfter, so that a send does not hold its slot or the queue
+// for as long as the server asks.
+func WithRetry(maxRetries int, initialBackoff time.Duration) ClientOption {
+	return func(c *Client) error {
+		if maxRetries < 0 {
+			return fmt.Errorf("max retries must not be negative")
+		}
+		if initialBackoff <= 0 {
+			return fmt.Errorf("retry backoff must be positive")
+		}
+		c.maxRetries = maxRetries
+		c.retryBackoff = initialBackoff
+		return nil
+	}
+}
+
+// WithMaxRetryAfter sets the longest Retry-After delay that sends without a
+// deadline wait for before retrying; a longer delay ends the retries.
+// The default is 5 minutes. Sends with a deadline wait as long as the deadline allows.
+func WithMaxRetryAfter(d time.Duration) ClientOption {
+	return func(c *Client) error {
+		if d <= 0 {
+			return fmt.Errorf("max retry after must be positive")
+		}
+		c.maxRetryAfter = d
+		return nil
+	}
+}
+
+// WithRetryableStatuses sets the HTTP status codes that trigger a retry,
+// replacing the default of 429 and 5xx. It has no effect unless retries
+// are enabled with WithRetry.
+func WithRetryableStatuses(codes ...int) ClientOption {
+	return func(c *Client) error {
+		statuses := make(map[int]bool, len(codes))
+		for _, code := range codes {
+			if code < 100 || code > 599 {
+				return fmt.Errorf("invalid retryable status %d", code)
+			}
+			statuses[code] = true
+		}
+		c.retryableStatuses = statuses
+		return nil
+	}
+}
+
+// WithRetryableBodyCodes retries responses with status 200 whose body reports
+// one of codes in its code field, such as a fork's temporary "server busy" code.
+// Like retried statuses, these responses are an error once the retries are exhausted.
+// It has no effect unless retries are enabled with WithRetry, and requires reading
+// every response body, decoded as set by WithResponseDecoder.
+func WithRetryableBodyCodes(codes ...int) ClientOption {
+	return func(c *Client) error {
+		if len(codes) == 0 {
+			return fmt.Errorf("at least one retryable body code is required")
+		}
+		retryable := make(map[int]bool, len(codes))
+		for _, code := range codes {
+			retryable[code] = true
+		}
+		c.retryableBodyCodes = retryable
+		return nil
+	}
+}
+
+// bodyCodeError is the error of a response whose status is 200 but whose body
+// reports a code that is not a success.
+type bodyCodeError struct {
+	code      int
+	retryable bool
+}
+
+func (e *bodyCodeError) Error() string { return fmt.Sprintf("unexpected body code: %d", e.code) }
+
+// checkBodyCode returns an error if the response body reports a retryable code,
+// or a code outside the success codes set by WithSuccessBodyCodes.
+// Bodies that cannot be decoded are accepted, since the status reported success.
+func (c *Client) checkBodyCode(body []byte) error {
+	resp, err := c.decodeResponse(body)
+	if err != nil {
+		return nil
+	}
+	if c.retryableBodyCodes[resp.Code] {
+		return &bodyCodeError{code: resp.Code, retryable: true}
+	}
+	if c.successBodyCodes != nil && !c.successBodyCodes[resp.Code] {
+		return &bodyCodeError{code: resp.Code}
+	}
+	return nil
+}
+
+// ErrorClass classifies the transport errors that occur when no response is received,
+// so that WithRetryableErrors can decide which to retry.
+type ErrorClass int
+
+const (
+	// ErrorOther is any transport error not covered by another class.
+	ErrorOther ErrorClass = iota
+	// ErrorTimeout is an attempt that timed out, for example because of WithTimeout.
+	ErrorTimeout
+	// ErrorDNS is a failure to resolve the server's host name.
+	ErrorDNS
+	// ErrorConnectionRefused is a connection refused by the server's host.
+	ErrorConnectionRefused
+	// ErrorTLS is a TLS handshake or certificate verification failure.
+	ErrorTLS
+)
+
+// String returns the name of the error class.
+func (c ErrorClass) String() string {
+	switch c {
+	case ErrorTimeout:
+		return "timeout"
+	case ErrorDNS:
+		return "dns"
+	case ErrorConnectionRefused:
+		return "connection refused"
+	case ErrorTLS:
+		return "tls"
+	default:
+		return "other"
+	}
+}
+
+// WithRetryableErrors sets the classes of transport errors that trigger a retry,
+// replacing the default of retrying every transport error. For example, passing
+// ErrorTimeout alone retries attempts that timed out but not refused connections.
+// It has no effect unless retries are enabled with WithRetry, and does not change
+// which statuses are retried.
+func WithRetryableErrors(classes ...ErrorClass) ClientOption {
+	return func(c *Client) error {
+		retryable := make(map[ErrorClass]bool, len(classes))
+		for _, class := range classes {
+			if class < ErrorOther || class > ErrorTLS {
+				return fmt.Errorf("invalid error class %d", class)
+			}
+			retryable[class] = true
+		}
+		c.retryableErrors = retryable
+		return nil
+	}
+}
+
+// classifyError returns the class of a transport error.
+func classifyError(err error) ErrorClass {
+	var (
+		dnsErr      *net.DNSError
+		certErr     *tls.CertificateVerificationError
+		recordErr   tls.RecordHeaderError
+		unknownAuth x509.UnknownAuthorityError
+		hostnameErr x509.HostnameError
+		invalidCert x509.CertificateInvalidError
+		netErr      net.Error
+	)
+	switch {
+	case errors.As(err, &dnsErr):
+		return ErrorDNS
+	case errors.As(err, &certErr), errors.As(err, &recordErr), errors.As(err, &unknownAuth),
+		errors.As(err, &hostnameErr), errors.As(err, &invalidCert):
+		return ErrorTLS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrorConnectionRefused
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorTimeout
+	default:
+		return ErrorOther
+	}
+}
+
+// WithBackoffJitter randomizes each retry backoff by up to the given fraction
+// in either direction, so that many clients retrying at once spread out.
+// For example, a fraction of 0.2 turns a 1s backoff into a wait between 0.8s and 1.2s.
+// The fraction must be between 0 and 1.
+func WithBackoffJitter(fraction float64) ClientOption {
+	return func(c *Client) error {
+		if fraction < 0 || fraction > 1 {
+			return fmt.Errorf("backoff jitter must be between 0 and 1, got %v", fraction)
+		}
+		c.backoffJitter = fraction
+		return nil
+	}
+}
+
+// WithTimeout limits each attempt to send a notification to d, including
+// connecting, sending the request and reading the response. When retries are
+// enabled with WithRetry, every retry gets a fresh timeout of d, so the total time
+// of a send can add up to several times d plus the backoffs; use WithRetryDeadline
+// to bound it.
+func WithTimeout(d time.Duration) ClientOption {
+	return func(c *Client) error {
+		if d <= 0 {
+			return fmt.Errorf("timeout must be positive")
+		}
+		c.attemptTimeout = d
+		return nil
+	}
+}
+
+// TimeoutEnv is the environment variable read by WithTimeoutFromEnv.
+const TimeoutEnv = "BARK_TIMEOUT"
+
+// WithTimeoutFromEnv sets the per-attempt timeout, as WithTimeout does, from the
+// BARK_TIMEOUT environment variable, a duration such as "5s" or "1m30s".
+// It has no effect if the variable is unset or empty, and NewClient fails if it
+// cannot be parsed or is not positive.
+func WithTimeoutFromEnv() ClientOption {
+	return func(c *Client) error {
+		value := os.Getenv(TimeoutEnv)
+		if value == "" {
+			return nil
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", TimeoutEnv, err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("invalid %s: timeout must be positive", TimeoutEnv)
+		}
+		c.attemptTimeout = d
+		return nil
+	}
+}
+
+// WithRetryDeadline bounds the total time spent sending a notification to d,
+// across all attempts and the backoffs between them. An attempt in flight when
+// the deadline is reached is cancelled, and no retry is started that would end past it.
+// If the context passed to Send has an earlier deadline, that deadline applies instead.
+func WithRetryDeadline(d time.Duration) ClientOption {
+	return func(c *Client) error {
+		if d <= 0 {
+			return fmt.Errorf("retry deadline must be positive")
+		}
+		c.retryDeadline = d
+		return nil
+	}
+}
+
+// retry calls attempt until it succeeds, the error is not retryable,
+// the retries are exhausted or the context is done, and returns the status code
+// and error of the last attempt. Each attempt runs with
+// its own context derived from ctx, and no retry is started if the backoff
+// would end past the deadline of ctx or the retry deadline.
+func (c *Client) retry(ctx context.Context, attempt func(ctx context.Context) (int, error)) (int, error) {
+	if c.retryDeadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.retryDeadline)
+		defer cancel()
+	}
+
+	for retries := 0; ; retries++ {
+		statusCode, err := c.attempt(ctx, attempt)
+
+		if err == nil || retries >= c.maxRetries || isPermanent(err) || !c.isRetryable(ctx, statusCode, err) {
+			return statusCode, err
+		}
+
+		wait := c.backoff(retries)
+		if delay, ok := retryAfter(err); ok {
+			if _, ok := ctx.Deadline(); !ok && delay > c.retryAfterLimit() {
+				return statusCode, fmt.Errorf("retry aborted: server asked to retry after %v, more than %v: %w", delay, c.retryAfterLimit(), err)
+			}
+			wait = delay
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= wait {
+			return statusCode, fmt.Errorf("retry aborted: %w (last error: %v)", context.DeadlineExceeded, err)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return statusCode, fmt.Errorf("retry aborted: %w (last error: %v)", ctx.Err(), err)
+		}
+	}
+}
+
+// retryAfterLimit returns the longest Retry-After delay waited for by sends without a deadline.
+func (c *Client) retryAfterLimit() time.Duration {
+	if c.maxRetryAfter > 0 {
+		return c.maxRetryAfter
+	}
+	return defaultMaxRetryAfter
+}
+
+// attempt calls attempt with its own context derived from ctx,
+// limited to the per-attempt timeout if one is set.
+func (c *Client) attempt(ctx context.Context, attempt func(ctx context.Context) (int, error)) (int, error) {
+	var cancel context.CancelFunc
+	if c.attemptTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.attemptTimeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	return attempt(ctx)
+}
+
+// permanentError marks an error that must not be retried, whatever its status code.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+func (e *permanentError) Unwrap() error { return e.err }
+
+// isPermanent reports whether err must not be retried.
+func isPermanent(err error) bool {
+	var perm *permanentError
+	return errors.As(err, &perm)
+}
+
+// retryAfterError is the error of a response carrying a Retry-After header,
+// whose delay replaces the backoff before the next retry.
+type retryAfterError struct {
+	err   error
+	delay time.Duration
+}
+
+func (e *retryAfterError) Error() string { return e.err.Error() }
+
+func (e *retryAfterError) Unwrap() error { return e.err }
+
+// retryAfter returns the delay requested by the server for the response that caused err, if any.
+func retryAfter(err error) (time.Duration, bool) {
+	var after *retryAfterError
+	if !errors.As(err, &after) {
+		return 0, false
+	}
+	return after.delay, true
+}
+
+// parseRetryAfter parses a Retry-After header value, either a number of seconds
+// or an HTTP date, into the delay to wait from now. A date in the past is no delay.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	return max(date.Sub(now), 0), true
+}
+
+// isRetryable reports whether a failed attempt with statusCode and err should be retried.
+// A status code of 0 means no response was received.
+func (c *Client) isRetryable(ctx context.Context, statusCode int, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if statusCode == 0 {
+		return c.retryableErrors == nil || c.retryableErrors[classifyError(err)]
+	}
+	var codeErr *bodyCodeError
+	if errors.As(err, &codeErr) {
+		return codeErr.retryable
+	}
+	if c.retryableStatuses != nil {
+		return c.retryableStatuses[statusCode]
+	}
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+// backoff returns the wait before the retry following the given number of retries.
+func (c *Client) backoff(retries int) time.Duration {
+	d := c.retryBackoff
+	for i := 0; i < retries && d < maxRetryBackoff; i++ {
+		d *= 2
+	}
+	if d > maxRetryBackoff {
+		d = maxRetryBackoff
+	}
+
+	if c.backoffJitter > 0 {
+		// Scale d by a random factor in [1-jitter, 1+jitter)
+		factor := 1 + c.backoffJitter*(2*c.randFloat()-1)
+		d = time.Duration(float64(d) * factor)
+	}
+	return d
+}
@@ -0,0 +1,555 @@
+package gobark
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// newFlakyServer returns a server that responds with status to the first failures requests
+// and with 200 OK afterwards, counting every request in hits.
+func newFlakyServer(t *testing.T, status, failures int, hits *int32) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if int(atomic.AddInt32(hits, 1)) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestWithRetry(t *testing.T) {
+	var hits int32
+	server := newFlakyServer(t, http.StatusServiceUnavailable, 2, &hits)
+
+	client, err := NewClient(server.URL, "test-key", WithRetry(3, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Send(context.Background(), "test message"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if got := atomic.LoadInt32(&hits); got != 3 {
+		t.Errorf("server hits = %d, want 3", got)
+	}
+}
+
+func TestWithRetry_RetryAfter(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryAfter string
+	}{
+		{name: "seconds", retryAfter: "0"},
+		{name: "http date", retryAfter: time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&hits, 1) == 1 {
+					w.Header().Set("Retry-After", tt.retryAfter)
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			}))
+			defer server.Close()
+
+			// The backoff outlasts the test timeout, so only the Retry-After delay lets the retry happen
+			client, err := NewClient(server.URL, "test-key", WithRetry(1, time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := client.Send(context.Background(), "test message"); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+			if got := atomic.LoadInt32(&hits); got != 2 {
+				t.Errorf("server hits = %d, want 2", got)
+			}
+		})
+	}
+}
+
+func TestWithRetry_RetryAfterLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []ClientOption
+		retryAfter string
+		wantErr    bool
+		wantHits   int32
+	}{
+		{name: "no deadline, delay over the default limit", retryAfter: "86400", wantErr: true, wantHits: 1},
+		{name: "no deadline, delay over a custom limit", opts: []ClientOption{WithMaxRetryAfter(time.Millisecond)}, retryAfter: "1", wantErr: true, wantHits: 1},
+		{name: "delay within the retry deadline", opts: []ClientOption{WithMaxRetryAfter(time.Millisecond), WithRetryDeadline(5 * time.Second)}, retryAfter: "1", wantHits: 2},
+		{name: "delay past the retry deadline", opts: []ClientOption{WithRetryDeadline(5 * time.Second)}, retryAfter: "86400", wantErr: true, wantHits: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&hits, 1) == 1 {
+					w.Header().Set("Retry-After", tt.retryAfter)
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			}))
+			defer server.Close()
+
+			client, err := NewClient(server.URL, "test-key", append(tt.opts, WithRetry(3, time.Millisecond))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Sends give up at once instead of waiting past their limit
+			err = client.Send(context.Background(), "test message")
+			if tt.wantErr && (err == nil || !strings.Contains(err.Error(), "retry aborted")) {
+				t.Fatalf("Send() error = %v, want the retry to be aborted", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+			if got := atomic.LoadInt32(&hits); got != tt.wantHits {
+				t.Errorf("server hits = %d, want %d", got, tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestWithMaxRetryAfter_Invalid(t *testing.T) {
+	if _, err := NewClient("", "test-key", WithMaxRetryAfter(0)); err == nil {
+		t.Error("NewClient() error = nil, want error")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{name: "seconds", value: "120", want: 2 * time.Minute, wantOK: true},
+		{name: "http date", value: "Fri, 01 Mar 2024 12:00:30 GMT", want: 30 * time.Second, wantOK: true},
+		{name: "past http date", value: "Fri, 01 Mar 2024 11:00:00 GMT", want: 0, wantOK: true},
+		{name: "empty", value: ""},
+		{name: "negative seconds", value: "-5"},
+		{name: "invalid", value: "soon"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseRetryAfter(tt.value, now)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("parseRetryAfter(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestWithRetryableBodyCodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int32
+		wantHits int32
+		wantErr  bool
+	}{
+		{name: "retried until success", failures: 2, wantHits: 3},
+		{name: "retries exhausted", failures: 5, wantHits: 3, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&hits, 1) <= tt.failures {
+					fmt.Fprint(w, `{"code":503,"message":"server busy"}`)
+					return
+				}
+				fmt.Fprint(w, `{"code":200,"message":"success"}`)
+			}))
+			defer server.Close()
+
+			client, err := NewClient(server.URL, "test-key", WithRetry(2, time.Millisecond), WithRetryableBodyCodes(503))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = client.Send(context.Background(), "test message")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := atomic.LoadInt32(&hits); got != tt.wantHits {
+				t.Errorf("server hits = %d, want %d", got, tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestWithRetryableStatuses(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		retryable []int
+		wantHits  int32
+		wantErr   bool
+	}{
+		{name: "custom status is retried", status: http.StatusBadGateway, retryable: []int{502}, wantHits: 3},
+		{name: "default status no longer retried", status: http.StatusServiceUnavailable, retryable: []int{502}, wantHits: 1, wantErr: true},
+		{name: "client error made retryable", status: http.StatusConflict, retryable: []int{409, 502}, wantHits: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits int32
+			server := newFlakyServer(t, tt.status, 2, &hits)
+
+			client, err := NewClient(server.URL, "test-key",
+				WithRetry(3, time.Millisecond),
+				WithRetryableStatuses(tt.retryable...),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = client.Send(context.Background(), "test message")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := atomic.LoadInt32(&hits); got != tt.wantHits {
+				t.Errorf("server hits = %d, want %d", got, tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestWithRetryableStatuses_Invalid(t *testing.T) {
+	if _, err := NewClient("", "test-key", WithRetryableStatuses(42)); err == nil {
+		t.Error("NewClient() error = nil, want error")
+	}
+}
+
+func TestWithBackoffJitter(t *testing.T) {
+	tests := []struct {
+		name    string
+		random  float64
+		retries int
+		want    time.Duration
+	}{
+		{name: "lowest random value", random: 0, retries: 0, want: 80 * time.Millisecond},
+		{name: "middle random value", random: 0.5, retries: 0, want: 100 * time.Millisecond},
+		{name: "high random value", random: 0.75, retries: 1, want: 220 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient("", "test-key",
+				WithRetry(3, 100*time.Millisecond),
+				WithBackoffJitter(0.2),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			client.randFloat = func() float64 { return tt.random }
+
+			got := client.backoff(tt.retries)
+			if got != tt.want {
+				t.Errorf("backoff(%d) = %v, want %v", tt.retries, got, tt.want)
+			}
+
+			base := 100 * time.Millisecond << tt.retries
+			if min, max := base*8/10, base*12/10; got < min || got > max {
+				t.Errorf("backoff(%d) = %v, want within [%v, %v]", tt.retries, got, min, max)
+			}
+		})
+	}
+}
+
+func TestWithBackoffJitter_Invalid(t *testing.T) {
+	for _, fraction := range []float64{-0.1, 1.5} {
+		if _, err := NewClient("", "test-key", WithBackoffJitter(fraction)); err == nil {
+			t.Errorf("WithBackoffJitter(%v) error = nil, want error", fraction)
+		}
+	}
+}
+
+func TestRetry_StopsAtParentDeadline(t *testing.T) {
+	var hits int32
+	server := newFlakyServer(t, http.StatusServiceUnavailable, 100, &hits)
+
+	client, err := NewClient(server.URL, "test-key", WithRetry(5, 50*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The deadline allows the first attempt and one 50ms backoff, but not the 100ms one after it
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = client.Send(ctx, "test message")
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Send() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed > 120*time.Millisecond {
+		t.Errorf("Send() took %v, want it to stop before the parent deadline", elapsed)
+	}
+	if got := atomic.LoadInt32(&hits); got != 2 {
+		t.Errorf("server hits = %d, want 2", got)
+	}
+}
+
+func TestWithTimeoutAndRetryDeadline(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		// Hang until the client gives up on the attempt
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "test-key",
+		WithTimeout(50*time.Millisecond),
+		WithRetry(10, 10*time.Millisecond),
+		WithRetryDeadline(200*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	err = client.Send(context.Background(), "test message")
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Send() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	// Without the retry deadline, 11 attempts of 50ms would take well over 500ms
+	if elapsed > 300*time.Millisecond {
+		t.Errorf("Send() took %v, want it bounded by the retry deadline", elapsed)
+	}
+	if got := atomic.LoadInt32(&hits); got < 2 || got > 4 {
+		t.Errorf("server hits = %d, want the per-attempt timeout to allow 2 to 4 attempts", got)
+	}
+}
+
+func TestWithTimeout_Invalid(t *testing.T) {
+	if _, err := NewClient("", "test-key", WithTimeout(0)); err == nil {
+		t.Error("NewClient() with zero timeout error = nil, want error")
+	}
+	if _, err := NewClient("", "test-key", WithRetryDeadline(-time.Second)); err == nil {
+		t.Error("NewClient() with negative retry deadline error = nil, want error")
+	}
+}
+
+func TestWithTimeoutFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "unset", value: "", want: 0},
+		{name: "duration", value: "1m30s", want: 90 * time.Second},
+		{name: "invalid", value: "soon", wantErr: true},
+		{name: "not positive", value: "-5s", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(TimeoutEnv, tt.value)
+
+			client, err := NewClient("", "test-key", WithTimeoutFromEnv())
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("NewClient() with %s=%q error = nil, want error", TimeoutEnv, tt.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+			if client.attemptTimeout != tt.want {
+				t.Errorf("attempt timeout = %v, want %v", client.attemptTimeout, tt.want)
+			}
+		})
+	}
+}
+
+// transportErrors simulates a transport error of each class.
+var transportErrors = map[ErrorClass]error{
+	ErrorTimeout:           os.ErrDeadlineExceeded,
+	ErrorDNS:               &net.DNSError{Err: "no such host", Name: "bark.example.com", IsNotFound: true},
+	ErrorConnectionRefused: &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)},
+	ErrorTLS:               x509.UnknownAuthorityError{},
+	ErrorOther:             errors.New("connection reset by peer"),
+}
+
+func TestClassifyError(t *testing.T) {
+	for want, err := range transportErrors {
+		// The HTTP client wraps transport errors in a *url.Error
+		wrapped := fmt.Errorf("failed to send request: %w", &url.Error{Op: "Get", URL: "https://bark.example.com", Err: err})
+		if got := classifyError(wrapped); got != want {
+			t.Errorf("classifyError(%v) = %v, want %v", err, got, want)
+		}
+	}
+}
+
+func TestWithRetryableErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		retryable []ErrorClass
+		class     ErrorClass
+		wantRetry bool
+	}{
+		{name: "timeout retried", retryable: []ErrorClass{ErrorTimeout}, class: ErrorTimeout, wantRetry: true},
+		{name: "refused not retried", retryable: []ErrorClass{ErrorTimeout}, class: ErrorConnectionRefused, wantRetry: false},
+		{name: "refused retried", retryable: []ErrorClass{ErrorConnectionRefused}, class: ErrorConnectionRefused, wantRetry: true},
+		{name: "timeout not retried", retryable: []ErrorClass{ErrorConnectionRefused}, class: ErrorTimeout, wantRetry: false},
+		{name: "dns not retried", retryable: []ErrorClass{ErrorTimeout}, class: ErrorDNS, wantRetry: false},
+		{name: "tls not retried", retryable: []ErrorClass{ErrorTimeout, ErrorDNS}, class: ErrorTLS, wantRetry: false},
+		{name: "tls retried", retryable: []ErrorClass{ErrorTLS}, class: ErrorTLS, wantRetry: true},
+		{name: "default retries everything", retryable: nil, class: ErrorDNS, wantRetry: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int
+			transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				attempts++
+				return nil, transportErrors[tt.class]
+			})
+
+			opts := []ClientOption{WithHTTPClient(&http.Client{Transport: transport}), WithRetry(2, time.Millisecond)}
+			if tt.retryable != nil {
+				opts = append(opts, WithRetryableErrors(tt.retryable...))
+			}
+			client, err := NewClient("https://bark.example.com", "test-key", opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := client.Send(context.Background(), "test message"); err == nil {
+				t.Fatal("Send() error = nil, want error")
+			}
+
+			wantAttempts := 1
+			if tt.wantRetry {
+				wantAttempts = 3
+			}
+			if attempts != wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, wantAttempts)
+			}
+		})
+	}
+}
+
+func TestRetry_AttemptContextDerivedFromParent(t *testing.T) {
+	type ctxKey struct{}
+	parent := context.WithValue(context.Background(), ctxKey{}, "parent")
+
+	client, err := NewClient("", "test-key", WithRetry(2, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var attempts int
+	_, err = client.retry(parent, func(ctx context.Context) (int, error) {
+		attempts++
+		if ctx.Value(ctxKey{}) != "parent" {
+			t.Error("attempt context is not derived from the parent context")
+		}
+		if ctx == parent {
+			t.Error("attempt reused the parent context")
+		}
+		return http.StatusBadGateway, errors.New("bad gateway")
+	})
+
+	if err == nil {
+		t.Error("retry() error = nil, want error")
+	}
+	if attempts != 3 {
+		t.Errorf("attempts = %d, want 3", attempts)
+	}
+}
+
+func TestWithDeadLetter(t *testing.T) {
+	var hits int32
+	server := newFlakyServer(t, http.StatusServiceUnavailable, 100, &hits)
+
+	type deadLetter struct {
+		body string
+		opts []Option
+		err  error
+	}
+	var letters []deadLetter
+	client, err := NewClient(server.URL, "test-key",
+		WithRetry(2, time.Millisecond),
+		WithDeadLetter(func(ctx context.Context, body string, opts []Option, err error) {
+			letters = append(letters, deadLetter{body: body, opts: opts, err: err})
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sendErr := client.Send(context.Background(), "backup failed", WithTitle("Backup"), WithGroup("ops"))
+	if sendErr == nil {
+		t.Fatal("Send() error = nil, want error")
+	}
+
+	if got := atomic.LoadInt32(&hits); got != 3 {
+		t.Errorf("server hits = %d, want 3", got)
+	}
+	if len(letters) != 1 {
+		t.Fatalf("dead letters = %d, want 1", len(letters))
+	}
+
+	letter := letters[0]
+	if letter.body != "backup failed" {
+		t.Errorf("dead letter body = %q, want %q", letter.body, "backup failed")
+	}
+	if letter.err != sendErr {
+		t.Errorf("dead letter error = %v, want %v", letter.err, sendErr)
+	}
+
+	// Replaying the options rebuilds the same notification
+	replayed := NewNotification(letter.body, letter.opts...)
+	if want := NewNotification("backup failed", WithTitle("Backup"), WithGroup("ops")); replayed.Fingerprint() != want.Fingerprint() {
+		t.Error("dead letter options do not rebuild the original notification")
+	}
+}
+
+func TestWithDeadLetter_NotCalledOnSuccess(t *testing.T) {
+	var called bool
+	client, _ := newRecordingClient(t, WithDeadLetter(func(context.Context, string, []Option, error) {
+		called = true
+	}))
+
+	if err := client.Send(context.Background(), "test message"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if called {
+		t.Error("dead letter handler called for a successful send")
+	}
+}
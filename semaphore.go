@@ -0,0 +1,34 @@
+package gobark
+
+import (
+	"context"
+	"fmt"
+)
+
+// WithMaxConcurrentSends limits the number of sends in flight at once across
+// all callers of the client to n. Further sends block until a slot is free or
+// their context is done. Unlike rate limits, this bounds concurrency rather than frequency.
+func WithMaxConcurrentSends(n int) ClientOption {
+	return func(c *Client) error {
+		if n <= 0 {
+			return fmt.Errorf("max concurrent sends must be positive")
+		}
+		c.sendSlots = make(chan struct{}, n)
+		return nil
+	}
+}
+
+// acquireSendSlot blocks until a send slot is available and returns the function
+// that releases it. Without a concurrency limit, it returns immediately.
+func (c *Client) acquireSendSlot(ctx context.Context) (release func(), err error) {
+	if c.sendSlots == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case c.sendSlots <- struct{}{}:
+		return func() { <-c.sendSlots }, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for a send slot: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,68 @@
+package gobark
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithMaxConcurrentSends(t *testing.T) {
+	const limit, sends = 3, 12
+
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if current <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "test-key", WithMaxConcurrentSends(limit))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < sends; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := client.Send(context.Background(), "test message"); err != nil {
+				t.Errorf("Send() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&maxInFlight); got > limit {
+		t.Errorf("max sends in flight = %d, want <= %d", got, limit)
+	}
+}
+
+func TestWithMaxConcurrentSends_RespectsContext(t *testing.T) {
+	client, _ := newRecordingClient(t, WithMaxConcurrentSends(1))
+
+	// Occupy the only slot
+	release, err := client.acquireSendSlot(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := client.Send(ctx, "test message"); err == nil {
+		t.Error("Send() error = nil, want context error while waiting for a slot")
+	}
+}
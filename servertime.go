@@ -0,0 +1,49 @@
+package gobark
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ServerTime returns the current time of the server, as reported in the timestamp
+// of its /ping response, with a precision of one second. It also records how far
+// the server clock is from the local clock, for WithServerTimeStamp.
+func (c *Client) ServerTime(ctx context.Context) (time.Time, error) {
+	body, err := c.ping(ctx)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	resp, err := c.decodeResponse(body)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to decode ping response: %w", err)
+	}
+	if resp.Timestamp <= 0 {
+		return time.Time{}, fmt.Errorf("server did not report its time")
+	}
+
+	serverTime := time.Unix(resp.Timestamp, 0)
+	c.serverClockOffset.Store(int64(serverTime.Sub(c.now())))
+	return serverTime, nil
+}
+
+// WithServerTimeStamp prefixes every body with the server time formatted with
+// layout, such as time.DateTime, so that times in audit notifications do not
+// depend on the local clock. The server time is the local time corrected by the
+// offset measured by the last call to ServerTime; until ServerTime succeeds,
+// the local time is used.
+func WithServerTimeStamp(layout string) ClientOption {
+	return func(c *Client) error {
+		if layout == "" {
+			return fmt.Errorf("time stamp layout must not be empty")
+		}
+		c.serverTimeLayout = layout
+		return nil
+	}
+}
+
+// serverNow returns the current time of the client clock corrected by the server clock offset.
+func (c *Client) serverNow() time.Time {
+	return c.now().Add(time.Duration(c.serverClockOffset.Load()))
+}
@@ -0,0 +1,81 @@
+package gobark
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServerTime(t *testing.T) {
+	// The server clock is a year ahead of the local clock
+	serverTime := time.Now().AddDate(1, 0, 0).Truncate(time.Second)
+	var sent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ping" {
+			fmt.Fprintf(w, `{"code":200,"message":"pong","timestamp":%d}`, serverTime.Unix())
+			return
+		}
+		sent = r.URL.Path
+	}))
+	defer server.Close()
+
+	const layout = "2006-01-02"
+	client, err := NewClient(server.URL, "test-key", WithServerTimeStamp(layout))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := client.ServerTime(context.Background())
+	if err != nil {
+		t.Fatalf("ServerTime() error = %v", err)
+	}
+	if !got.Equal(serverTime) {
+		t.Errorf("ServerTime() = %v, want %v", got, serverTime)
+	}
+
+	if err := client.Send(context.Background(), "audit entry", WithTitle("")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	want := "/test-key/" + serverTime.Format(layout) + " audit entry"
+	if sent != want {
+		t.Errorf("path = %q, want %q", sent, want)
+	}
+}
+
+func TestServerTime_NoTimestamp(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"code":200,"message":"pong"}`)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "test-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.ServerTime(context.Background()); err == nil || !strings.Contains(err.Error(), "did not report") {
+		t.Errorf("ServerTime() error = %v, want a missing time error", err)
+	}
+}
+
+func TestWithServerTimeStamp_LocalClockBeforeSync(t *testing.T) {
+	client, transport := newRecordingClient(t, WithServerTimeStamp("2006-01-02"))
+
+	if err := client.Send(context.Background(), "audit entry"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	body := transport.Requests()[0].Body
+	stamp, rest, _ := strings.Cut(body, " ")
+	stampTime, err := time.ParseInLocation("2006-01-02", stamp, time.Local)
+	if err != nil || rest != "audit entry" {
+		t.Fatalf("body = %q, want a date followed by the body", body)
+	}
+	if d := time.Since(stampTime); d < 0 || d > 48*time.Hour {
+		t.Errorf("stamp = %q, want the local date", stamp)
+	}
+}
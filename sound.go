@@ -0,0 +1,236 @@
+package gobark
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// previewBody is the body of the notification sent by PreviewSound.
+const previewBody = "Preview"
+
+// silentSound is the built-in sound of the Bark app that plays nothing, sent by WithNoSound.
+const silentSound = "silence"
+
+// builtinSounds lists the notification sounds bundled with the Bark app, in their canonical case.
+var builtinSounds = []string{
+	"alarm", "anticipate", "bell", "birdsong", "bloom", "calypso", "chime", "choo",
+	"descent", "electronic", "fanfare", "glass", "gotosleep", "healthnotification",
+	"horn", "ladder", "mailsent", "minuet", "multiwayinvitation", "newmail",
+	"newsflash", "noir", "paymentsuccess", "shake", "sherwoodforest", "silence",
+	"spell", "suspense", "telegraph", "tiptoes", "typewriters", "update",
+}
+
+// ErrUnknownSound is returned by Send under WithStrictSounds when the server
+// does not list the requested sound.
+var ErrUnknownSound = errors.New("sound not available on server")
+
+// soundCache holds the sounds reported by the server, once fetched.
+type soundCache struct {
+	mu      sync.Mutex
+	sounds  []string
+	fetched bool
+	// fetching is closed when the fetch in progress completes, or nil if none is.
+	fetching chan struct{}
+}
+
+// WithNoSound explicitly silences the notification by sending the "silence" sound,
+// a silent sound bundled with the Bark app, rather than leaving the sound out,
+// which plays the default sound. It overrides WithDefaultSound and the "alarm"
+// sound of critical alerts, and is replaced by a later WithSound.
+func WithNoSound() Option {
+	return WithSound(silentSound)
+}
+
+// WithCategory sets the category of the notification, such as "deploy" or
+// "error". The category is not sent to the server; it selects the sound of
+// notifications without WithSound from the sounds set by WithCategorySounds.
+func WithCategory(category string) Option {
+	return func(n *Notification) {
+		n.category = category
+	}
+}
+
+// WithCategorySounds sets the sound of notifications by category, for
+// notifications whose sound is not set with WithSound. The category sound
+// takes precedence over WithDefaultSound.
+func WithCategorySounds(sounds map[string]string) ClientOption {
+	return func(c *Client) error {
+		categorySounds := make(map[string]string, len(sounds))
+		for category, sound := range sounds {
+			categorySounds[category] = sound
+		}
+		c.categorySounds = categorySounds
+		return nil
+	}
+}
+
+// WithSoundPreferring plays the first of sounds that the server reports as
+// available through its /sounds endpoint (see Client.Sounds). If the server
+// reports none of them, the notification is sent without a sound, which plays
+// the default sound; if the server cannot report its sounds, the first of sounds is used.
+// It replaces any sound set with WithSound, and is replaced by a later WithSound.
+func WithSoundPreferring(sounds []string) Option {
+	return func(n *Notification) {
+		n.sound = ""
+		n.soundPreferences = sounds
+	}
+}
+
+// Sounds returns the names of the sounds available on the server, as listed by
+// its /sounds endpoint in the data field of the response. The stock Bark server
+// has no such endpoint: it requires a server that reports its sounds.
+// The list is fetched on first use and cached until Reset is called; failures are not cached.
+// Concurrent calls share a single fetch, and stop waiting for it once their context is done.
+func (c *Client) Sounds(ctx context.Context) ([]string, error) {
+	for {
+		c.soundCache.mu.Lock()
+		if c.soundCache.fetched {
+			sounds := c.soundCache.sounds
+			c.soundCache.mu.Unlock()
+			return sounds, nil
+		}
+
+		// Wait for the fetch in progress, if any, then check the cache again
+		if fetching := c.soundCache.fetching; fetching != nil {
+			c.soundCache.mu.Unlock()
+			select {
+			case <-fetching:
+				continue
+			case <-ctx.Done():
+				return nil, fmt.Errorf("failed to fetch sounds: %w", ctx.Err())
+			}
+		}
+
+		done := make(chan struct{})
+		c.soundCache.fetching = done
+		c.soundCache.mu.Unlock()
+
+		sounds, err := c.fetchSounds(ctx)
+
+		c.soundCache.mu.Lock()
+		c.soundCache.fetching = nil
+		if err == nil {
+			c.soundCache.sounds, c.soundCache.fetched = sounds, true
+		}
+		c.soundCache.mu.Unlock()
+		close(done)
+
+		return sounds, err
+	}
+}
+
+// fetchSounds requests the sounds listed by the /sounds endpoint of the server.
+func (c *Client) fetchSounds(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, joinURL(c.baseURL, "sounds"), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if c.hostHeader != "" {
+		req.Host = c.hostHeader
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sounds: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch sounds: unexpected status code: %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Data []string `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode sounds: %w", err)
+	}
+	return body.Data, nil
+}
+
+// resolveSound sets the sound of n to its first preferred sound available on the server.
+func (c *Client) resolveSound(ctx context.Context, n *Notification) {
+	if len(n.soundPreferences) == 0 {
+		return
+	}
+
+	available, err := c.Sounds(ctx)
+	if err != nil {
+		n.sound = n.soundPreferences[0]
+		return
+	}
+
+	n.sound = ""
+	for _, preferred := range n.soundPreferences {
+		for _, sound := range available {
+			if strings.EqualFold(preferred, sound) {
+				n.sound = sound
+				return
+			}
+		}
+	}
+}
+
+// WithStrictSounds makes sends fail with ErrUnknownSound when their sound is not
+// among those the server lists through its /sounds endpoint (see Client.Sounds),
+// instead of the device silently playing the default sound. Names are matched
+// case-insensitively. Servers that cannot report their sounds are not checked.
+func WithStrictSounds() ClientOption {
+	return func(c *Client) error {
+		c.strictSounds = true
+		return nil
+	}
+}
+
+// checkSound reports an error if strict sounds are enabled and the server
+// reports its sounds without the sound of n.
+func (c *Client) checkSound(ctx context.Context, n *Notification) error {
+	if !c.strictSounds || n.sound == "" || n.sound == silentSound {
+		return nil
+	}
+
+	available, err := c.Sounds(ctx)
+	if err != nil {
+		return nil
+	}
+	for _, sound := range available {
+		if strings.EqualFold(n.sound, sound) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q", ErrUnknownSound, n.sound)
+}
+
+// WithSoundNormalization matches sound names case-insensitively against the
+// sounds bundled with the Bark app and sends them in their canonical case,
+// so that "Bell" is sent as "bell". Other sound names are sent unchanged.
+func WithSoundNormalization() ClientOption {
+	return func(c *Client) error {
+		c.normalizeSound = true
+		return nil
+	}
+}
+
+// canonicalSound returns the canonical name of a built-in sound matching sound
+// regardless of case, or sound itself if there is no match.
+func canonicalSound(sound string) string {
+	for _, builtin := range builtinSounds {
+		if strings.EqualFold(sound, builtin) {
+			return builtin
+		}
+	}
+	return sound
+}
+
+// PreviewSound sends a minimal notification that plays sound, so that a user can
+// hear it before choosing it. The notification is titled with the sound name.
+func (c *Client) PreviewSound(ctx context.Context, sound string) error {
+	if sound == "" {
+		return fmt.Errorf("sound is required")
+	}
+	return c.Send(ctx, previewBody, WithTitle(sound), WithSound(sound))
+}
@@ -0,0 +1,289 @@
+package gobark
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithSoundNormalization(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []ClientOption
+		sound     string
+		wantSound string
+	}{
+		{name: "mixed case built-in sound", opts: []ClientOption{WithSoundNormalization()}, sound: "Bell", wantSound: "bell"},
+		{name: "upper case built-in sound", opts: []ClientOption{WithSoundNormalization()}, sound: "MINUET", wantSound: "minuet"},
+		{name: "custom sound unchanged", opts: []ClientOption{WithSoundNormalization()}, sound: "MyCustomSound", wantSound: "MyCustomSound"},
+		{name: "normalization disabled", sound: "Bell", wantSound: "Bell"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, transport := newRecordingClient(t, tt.opts...)
+
+			if err := client.Send(context.Background(), "test message", WithSound(tt.sound)); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			if got := transport.Requests()[0].Params["sound"]; got != tt.wantSound {
+				t.Errorf("sound = %q, want %q", got, tt.wantSound)
+			}
+		})
+	}
+}
+
+func TestPreviewSound(t *testing.T) {
+	client, transport := newRecordingClient(t)
+
+	if err := client.PreviewSound(context.Background(), "minuet"); err != nil {
+		t.Fatalf("PreviewSound() error = %v", err)
+	}
+
+	req := transport.Requests()[0]
+	if req.Params["sound"] != "minuet" {
+		t.Errorf("sound = %q, want %q", req.Params["sound"], "minuet")
+	}
+	if req.Body != previewBody {
+		t.Errorf("body = %q, want %q", req.Body, previewBody)
+	}
+
+	if err := client.PreviewSound(context.Background(), ""); err == nil {
+		t.Error("PreviewSound(\"\") error = nil, want error")
+	}
+}
+
+// newSoundServer starts a server that lists sounds at /sounds and records the
+// sound of every notification it receives, counting the requests to /sounds.
+func newSoundServer(t *testing.T, sounds []string) (*httptest.Server, *[]string, *int) {
+	t.Helper()
+
+	var (
+		sent    []string
+		fetches int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/sounds" {
+			fetches++
+			json.NewEncoder(w).Encode(map[string]interface{}{"code": 200, "message": "success", "data": sounds})
+			return
+		}
+		sent = append(sent, r.URL.Query().Get("sound"))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &sent, &fetches
+}
+
+func TestSounds_ConcurrentFetch(t *testing.T) {
+	release := make(chan struct{})
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		<-release
+		json.NewEncoder(w).Encode(map[string]interface{}{"code": 200, "message": "success", "data": []string{"bell"}})
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "test-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := make(chan error, 1)
+	go func() {
+		_, err := client.Sounds(context.Background())
+		first <- err
+	}()
+	for fetches.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// A caller waiting for the hanging fetch gives up with its context
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := client.Sounds(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Sounds() while fetching error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	close(release)
+	if err := <-first; err != nil {
+		t.Fatalf("Sounds() error = %v", err)
+	}
+	sounds, err := client.Sounds(context.Background())
+	if err != nil || !reflect.DeepEqual(sounds, []string{"bell"}) {
+		t.Errorf("Sounds() = %q, %v, want the cached sounds", sounds, err)
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("fetched %d times, want 1", n)
+	}
+}
+
+func TestWithSoundPreferring(t *testing.T) {
+	server, sent, fetches := newSoundServer(t, []string{"alarm", "bell", "minuet"})
+
+	client, err := NewClient(server.URL, "test-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	sends := [][]Option{
+		{WithSoundPreferring([]string{"custom-chime", "Minuet", "bell"})},
+		{WithSoundPreferring([]string{"custom-chime"})},
+		{WithSoundPreferring([]string{"bell"}), WithSound("custom-chime")},
+	}
+	for _, opts := range sends {
+		if err := client.Send(ctx, "test message", opts...); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	want := []string{"minuet", "", "custom-chime"}
+	if !reflect.DeepEqual(*sent, want) {
+		t.Errorf("sent sounds = %q, want %q", *sent, want)
+	}
+	if *fetches != 1 {
+		t.Errorf("fetched sounds %d times, want 1", *fetches)
+	}
+}
+
+func TestWithSoundPreferring_NoSoundsEndpoint(t *testing.T) {
+	client, lastRequest := newCaptureClient(t)
+
+	// The capture server answers /sounds without a sound list, so the first preference is used
+	if err := client.Send(context.Background(), "test message", WithSoundPreferring([]string{"bell", "alarm"})); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if got := lastRequest().URL.Query().Get("sound"); got != "bell" {
+		t.Errorf("sound = %q, want %q", got, "bell")
+	}
+}
+
+func TestWithStrictSounds(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []ClientOption
+		sound    string
+		wantErr  error
+		wantSent []string
+	}{
+		{name: "listed sound", opts: []ClientOption{WithStrictSounds()}, sound: "bell", wantSent: []string{"bell"}},
+		{name: "listed sound in other case", opts: []ClientOption{WithStrictSounds()}, sound: "Bell", wantSent: []string{"Bell"}},
+		{name: "unknown sound", opts: []ClientOption{WithStrictSounds()}, sound: "custom-chime", wantErr: ErrUnknownSound},
+		{name: "unknown sound without strict mode", sound: "custom-chime", wantSent: []string{"custom-chime"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, sent, _ := newSoundServer(t, []string{"alarm", "bell", "minuet"})
+
+			client, err := NewClient(server.URL, "test-key", tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = client.Send(context.Background(), "test message", WithSound(tt.sound))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Send() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(*sent, tt.wantSent) {
+				t.Errorf("sent sounds = %q, want %q", *sent, tt.wantSent)
+			}
+		})
+	}
+}
+
+func TestWithStrictSounds_NoSoundsEndpoint(t *testing.T) {
+	client, lastRequest := newCaptureClient(t, WithStrictSounds())
+
+	// The capture server answers /sounds without a sound list, so the sound is not checked
+	if err := client.Send(context.Background(), "test message", WithSound("custom-chime")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if got := lastRequest().URL.Query().Get("sound"); got != "custom-chime" {
+		t.Errorf("sound = %q, want %q", got, "custom-chime")
+	}
+}
+
+func TestWithNoSound(t *testing.T) {
+	tests := []struct {
+		name       string
+		clientOpts []ClientOption
+		opts       []Option
+	}{
+		{name: "plain notification", opts: []Option{WithNoSound()}},
+		{name: "critical alert", opts: []Option{WithCriticalNotify(), WithNoSound()}},
+		{name: "client default sound", clientOpts: []ClientOption{WithDefaultSound("bell")}, opts: []Option{WithNoSound()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, transport := newRecordingClient(t, tt.clientOpts...)
+
+			if err := client.Send(context.Background(), "test message", tt.opts...); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			if got := transport.Requests()[0].Params["sound"]; got != "silence" {
+				t.Errorf("sound = %q, want %q", got, "silence")
+			}
+		})
+	}
+}
+
+func TestWithCategorySounds(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []Option
+		wantSound string
+	}{
+		{name: "mapped category", opts: []Option{WithCategory("deploy")}, wantSound: "bell"},
+		{name: "other mapped category", opts: []Option{WithCategory("error")}, wantSound: "alarm"},
+		{name: "explicit sound", opts: []Option{WithCategory("deploy"), WithSound("glass")}, wantSound: "glass"},
+		{name: "unmapped category", opts: []Option{WithCategory("info")}, wantSound: "chime"},
+		{name: "no category", wantSound: "chime"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, transport := newRecordingClient(t,
+				WithCategorySounds(map[string]string{"deploy": "bell", "error": "alarm"}),
+				WithDefaultSound("chime"),
+			)
+
+			if err := client.Send(context.Background(), "test message", tt.opts...); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			if got := transport.Requests()[0].Params["sound"]; got != tt.wantSound {
+				t.Errorf("sound = %q, want %q", got, tt.wantSound)
+			}
+		})
+	}
+}
+
+func TestWithCategorySounds_CopiesMap(t *testing.T) {
+	sounds := map[string]string{"deploy": "bell"}
+	client, transport := newRecordingClient(t, WithCategorySounds(sounds))
+
+	// Changing the map after NewClient does not affect the client
+	sounds["deploy"] = "alarm"
+
+	if err := client.Send(context.Background(), "test message", WithCategory("deploy")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if got := transport.Requests()[0].Params["sound"]; got != "bell" {
+		t.Errorf("sound = %q, want %q", got, "bell")
+	}
+}
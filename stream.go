@@ -0,0 +1,96 @@
+package gobark
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// KeyResult is the outcome of sending a notification to one device with SendToKeysStream.
+type KeyResult struct {
+	// Key is the key of the device.
+	Key string
+	// Err is the error of the send, or nil if the notification was delivered.
+	Err error
+}
+
+// WithStreamBuffer sets the buffer size of the channel returned by SendToKeysStream.
+// Workers block while the buffer is full until the consumer reads a result, so
+// with n = 0, an unbuffered channel, every send waits for the consumer.
+// The default is the number of workers, the broadcast concurrency.
+func WithStreamBuffer(n int) ClientOption {
+	return func(c *Client) error {
+		if n < 0 {
+			return fmt.Errorf("stream buffer must not be negative")
+		}
+		c.streamBuffer = &n
+		return nil
+	}
+}
+
+// SendToKeysStream sends the same notification to every device in keys, one
+// request per device, with at most the broadcast concurrency in flight at once.
+// Unlike SendToKeys, it works with servers without batch support, and reports
+// each device's result on the returned channel as soon as it is known, in no
+// particular order. The channel is closed once every key has a result.
+// An error is returned, and nothing is sent, if the notification is invalid.
+// Notifications are not deduplicated nor passed to the dead letter handler.
+func (c *Client) SendToKeysStream(ctx context.Context, keys []string, body string, opts ...Option) (<-chan KeyResult, error) {
+	n, err := c.newNotification(body, opts)
+	if err != nil {
+		return nil, err
+	}
+	if c.belowMinLevel(n) {
+		return nil, ErrBelowMinLevel
+	}
+
+	workers := c.broadcastConcurrency
+	if workers == 0 {
+		workers = defaultBroadcastConcurrency
+	}
+	buffer := workers
+	if c.streamBuffer != nil {
+		buffer = *c.streamBuffer
+	}
+
+	results := make(chan KeyResult, buffer)
+	pending := make(chan string)
+	var wg sync.WaitGroup
+	for w := 0; w < min(len(keys), workers); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range pending {
+				results <- KeyResult{Key: key, Err: c.sendToKey(ctx, n, key)}
+			}
+		}()
+	}
+
+	go func() {
+		for _, key := range keys {
+			pending <- key
+		}
+		close(pending)
+		wg.Wait()
+		close(results)
+	}()
+
+	return results, nil
+}
+
+// sendToKey sends a copy of the prepared notification n to the device with key.
+func (c *Client) sendToKey(ctx context.Context, n *Notification, key string) (err error) {
+	defer recoverPanic(&err)
+
+	copied := *n
+	_, err = c.send(withKeyOverride(ctx, key), &copied)
+	return err
+}
+
+// keyOverrideKey is the context key of the key that replaces the client's key for a send.
+type keyOverrideKey struct{}
+
+// withKeyOverride returns a context that makes resolveKey return key.
+func withKeyOverride(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, keyOverrideKey{}, key)
+}
@@ -0,0 +1,91 @@
+package gobark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestSendToKeysStream(t *testing.T) {
+	client, transport := newRecordingClient(t, WithKeyPattern(regexp.MustCompile(`^[a-z0-9-]+$`)))
+
+	keys := []string{"key-1", "key-2", "Invalid Key"}
+	results, err := client.SendToKeysStream(context.Background(), keys, "deploy finished")
+	if err != nil {
+		t.Fatalf("SendToKeysStream() error = %v", err)
+	}
+
+	failed := map[string]error{}
+	var delivered []string
+	for result := range results {
+		if result.Err != nil {
+			failed[result.Key] = result.Err
+			continue
+		}
+		delivered = append(delivered, result.Key)
+	}
+
+	sort.Strings(delivered)
+	if fmt.Sprint(delivered) != fmt.Sprint(keys[:2]) {
+		t.Errorf("delivered = %q, want %q", delivered, keys[:2])
+	}
+	if !errors.Is(failed["Invalid Key"], ErrInvalidKey) || len(failed) != 1 {
+		t.Errorf("failures = %v, want %q to fail with %v", failed, "Invalid Key", ErrInvalidKey)
+	}
+
+	var sentKeys []string
+	for _, req := range transport.Requests() {
+		sentKeys = append(sentKeys, req.Key)
+	}
+	sort.Strings(sentKeys)
+	if fmt.Sprint(sentKeys) != fmt.Sprint(keys[:2]) {
+		t.Errorf("sent to %q, want one request per valid key", sentKeys)
+	}
+}
+
+func TestWithStreamBuffer(t *testing.T) {
+	for _, buffer := range []int{0, 5} {
+		t.Run(fmt.Sprintf("buffer %d", buffer), func(t *testing.T) {
+			client, transport := newRecordingClient(t, WithStreamBuffer(buffer), WithBroadcastConcurrency(2))
+
+			keys := []string{"key-1", "key-2", "key-3", "key-4", "key-5"}
+			results, err := client.SendToKeysStream(context.Background(), keys, "deploy finished")
+			if err != nil {
+				t.Fatalf("SendToKeysStream() error = %v", err)
+			}
+
+			// A slow consumer: with a buffer as large as the keys, every send
+			// completes before the first result is read
+			if buffer >= len(keys) {
+				deadline := time.Now().Add(5 * time.Second)
+				for len(transport.Requests()) < len(keys) {
+					if time.Now().After(deadline) {
+						t.Fatalf("sent %d requests before reading, want %d", len(transport.Requests()), len(keys))
+					}
+					time.Sleep(time.Millisecond)
+				}
+			}
+
+			var count int
+			for result := range results {
+				if result.Err != nil {
+					t.Errorf("key %q error = %v", result.Key, result.Err)
+				}
+				count++
+			}
+			if count != len(keys) {
+				t.Errorf("received %d results, want %d", count, len(keys))
+			}
+		})
+	}
+}
+
+func TestWithStreamBuffer_Negative(t *testing.T) {
+	if _, err := NewClient("", "test-key", WithStreamBuffer(-1)); err == nil {
+		t.Error("NewClient() error = nil, want error")
+	}
+}
@@ -0,0 +1,32 @@
+package gobark
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NewTopicClient creates a new Bark client that sends to a topic instead of a single device.
+// On servers that support topics, a topic key is used in place of a device key
+// and delivers each notification to every device subscribed to the topic.
+// Notifications are sent exactly as with a device key, so the server must
+// recognize topic as a topic for it to reach more than one device.
+// The topic must be a single URL path segment: it must not be empty or contain
+// slashes or whitespace.
+func NewTopicClient(baseURL, topic string, opts ...ClientOption) (*Client, error) {
+	if err := validateTopic(topic); err != nil {
+		return nil, err
+	}
+
+	return newClient(baseURL, topic, nil, opts)
+}
+
+// validateTopic checks that topic can be used in place of a device key.
+func validateTopic(topic string) error {
+	if topic == "" {
+		return fmt.Errorf("topic is required")
+	}
+	if strings.ContainsAny(topic, "/ \t\r\n") {
+		return fmt.Errorf("invalid topic %q: must not contain slashes or whitespace", topic)
+	}
+	return nil
+}
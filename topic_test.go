@@ -0,0 +1,35 @@
+package gobark
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/xpzouying/gobark/barktest"
+)
+
+func TestNewTopicClient(t *testing.T) {
+	transport := &barktest.RecordingTransport{}
+	client, err := NewTopicClient("https://bark.example.com", "ops-oncall",
+		WithHTTPClient(&http.Client{Transport: transport}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Send(context.Background(), "db01 down", WithTitle("Alert")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	want := "https://bark.example.com/ops-oncall/Alert/db01%20down"
+	if got := transport.Requests()[0].URL; got != want {
+		t.Errorf("url = %q, want %q", got, want)
+	}
+}
+
+func TestNewTopicClient_InvalidTopic(t *testing.T) {
+	for _, topic := range []string{"", "ops/oncall", "ops oncall"} {
+		if _, err := NewTopicClient("", topic); err == nil {
+			t.Errorf("NewTopicClient(%q) error = nil, want error", topic)
+		}
+	}
+}
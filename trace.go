@@ -0,0 +1,91 @@
+package gobark
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// TraceInfo reports the timings of the phases of a request, for diagnosing slow sends.
+// A phase that did not happen, such as the DNS lookup and connect of a request on a
+// reused connection, has a zero duration.
+type TraceInfo struct {
+	// DNS is the time spent looking up the server's host.
+	DNS time.Duration
+	// Connect is the time spent establishing the TCP connection.
+	Connect time.Duration
+	// TLSHandshake is the time spent on the TLS handshake.
+	TLSHandshake time.Duration
+	// FirstByte is the time from sending the request to receiving the first byte of the response.
+	FirstByte time.Duration
+	// Reused reports whether the request was sent on a previously used connection.
+	Reused bool
+}
+
+// WithHTTPTrace calls cb with the timings of every request, retries included,
+// once its response headers have been received or it has failed.
+func WithHTTPTrace(cb func(info TraceInfo)) ClientOption {
+	return func(c *Client) error {
+		if cb == nil {
+			return fmt.Errorf("trace callback must not be nil")
+		}
+		c.httpTrace = cb
+		return nil
+	}
+}
+
+// requestTrace collects the timings of a single request.
+type requestTrace struct {
+	mu                               sync.Mutex
+	info                             TraceInfo
+	dnsStart, connectStart, tlsStart time.Time
+	sentAt                           time.Time
+}
+
+// withTrace returns req traced from sentAt, and a function reporting its timings
+// to the WithHTTPTrace callback. Without the option, req is returned unchanged.
+func (c *Client) withTrace(req *http.Request, sentAt time.Time) (*http.Request, func()) {
+	if c.httpTrace == nil {
+		return req, func() {}
+	}
+
+	t := &requestTrace{sentAt: sentAt}
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { t.record(func() { t.dnsStart = time.Now() }) },
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.record(func() { t.info.DNS = time.Since(t.dnsStart) })
+		},
+		ConnectStart: func(string, string) { t.record(func() { t.connectStart = time.Now() }) },
+		ConnectDone: func(string, string, error) {
+			t.record(func() { t.info.Connect = time.Since(t.connectStart) })
+		},
+		TLSHandshakeStart: func() { t.record(func() { t.tlsStart = time.Now() }) },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.record(func() { t.info.TLSHandshake = time.Since(t.tlsStart) })
+		},
+		GotConn: func(info httptrace.GotConnInfo) { t.record(func() { t.info.Reused = info.Reused }) },
+		GotFirstResponseByte: func() {
+			t.record(func() { t.info.FirstByte = time.Since(t.sentAt) })
+		},
+	}
+
+	traced := req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+	return traced, func() {
+		t.mu.Lock()
+		info := t.info
+		t.mu.Unlock()
+		c.httpTrace(info)
+	}
+}
+
+// record applies update to the trace. The transport may call the hooks from
+// its dialing goroutines, so updates are serialized.
+func (t *requestTrace) record(update func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	update()
+}
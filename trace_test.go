@@ -0,0 +1,77 @@
+package gobark
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithHTTPTrace(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	transport := &http.Transport{}
+	defer transport.CloseIdleConnections()
+
+	var infos []TraceInfo
+	// Address the server by name so that the send looks it up
+	baseURL := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	client, err := NewClient(baseURL, "test-key",
+		WithHTTPClient(&http.Client{Transport: transport}),
+		WithHTTPTrace(func(info TraceInfo) { infos = append(infos, info) }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if err := client.Send(ctx, "test message"); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	if len(infos) != 2 {
+		t.Fatalf("traced %d requests, want 2", len(infos))
+	}
+	first, second := infos[0], infos[1]
+	if first.DNS <= 0 || first.Connect <= 0 || first.FirstByte <= 0 {
+		t.Errorf("first trace = %+v, want DNS, connect and first byte timings", first)
+	}
+	if first.Reused {
+		t.Errorf("first trace Reused = true, want a new connection")
+	}
+	if !second.Reused || second.DNS != 0 || second.Connect != 0 {
+		t.Errorf("second trace = %+v, want a reused connection without DNS or connect", second)
+	}
+}
+
+func TestWithHTTPTrace_TLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	var info TraceInfo
+	client, err := NewClient(server.URL, "test-key",
+		WithHTTPClient(server.Client()),
+		WithHTTPTrace(func(i TraceInfo) { info = i }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Send(context.Background(), "test message"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if info.TLSHandshake <= 0 || info.FirstByte <= 0 {
+		t.Errorf("trace = %+v, want TLS handshake and first byte timings", info)
+	}
+}
+
+func TestWithHTTPTrace_Nil(t *testing.T) {
+	if _, err := NewClient("https://bark.example.com", "test-key", WithHTTPTrace(nil)); err == nil {
+		t.Error("NewClient() error = nil, want error")
+	}
+}
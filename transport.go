@@ -0,0 +1,97 @@
+package gobark
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	// defaultConnectTimeout and defaultKeepAlive match the dialer of http.DefaultTransport.
+	defaultConnectTimeout = 30 * time.Second
+	defaultKeepAlive      = 30 * time.Second
+)
+
+// WithConnectTimeout limits how long establishing a TCP connection to the server
+// may take, independently of the overall request timeout.
+func WithConnectTimeout(d time.Duration) ClientOption {
+	return func(c *Client) error {
+		if d <= 0 {
+			return fmt.Errorf("connect timeout must be positive")
+		}
+		c.connectTimeout = d
+		return nil
+	}
+}
+
+// WithResolver sets the DNS resolver used to look up the server's host,
+// for example to use a specific DNS server inside a container.
+func WithResolver(resolver *net.Resolver) ClientOption {
+	return func(c *Client) error {
+		if resolver == nil {
+			return fmt.Errorf("resolver must not be nil")
+		}
+		c.resolver = resolver
+		return nil
+	}
+}
+
+// WithTLSMinVersion refuses to connect to servers that do not support at least
+// TLS version v, such as tls.VersionTLS12, for compliance requirements.
+func WithTLSMinVersion(v uint16) ClientOption {
+	return func(c *Client) error {
+		switch v {
+		case tls.VersionTLS10, tls.VersionTLS11, tls.VersionTLS12, tls.VersionTLS13:
+		default:
+			return fmt.Errorf("unsupported tls version %#x", v)
+		}
+		c.tlsMinVersion = v
+		return nil
+	}
+}
+
+// configureTransport applies the connection options to the HTTP client's transport.
+// The transport is cloned so that a client passed to WithHTTPClient is not modified.
+func (c *Client) configureTransport() error {
+	configureDialer := c.connectTimeout > 0 || c.resolver != nil
+	if !configureDialer && c.tlsMinVersion == 0 {
+		return nil
+	}
+
+	var transport *http.Transport
+	switch t := c.client.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		return fmt.Errorf("connection options require an *http.Transport, got %T", t)
+	}
+
+	if configureDialer {
+		dialer := &net.Dialer{
+			Timeout:   defaultConnectTimeout,
+			KeepAlive: defaultKeepAlive,
+			Resolver:  c.resolver,
+		}
+		if c.connectTimeout > 0 {
+			dialer.Timeout = c.connectTimeout
+		}
+		transport.DialContext = dialer.DialContext
+	}
+
+	if c.tlsMinVersion != 0 {
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+		transport.TLSClientConfig.MinVersion = c.tlsMinVersion
+	}
+
+	httpClient := *c.client
+	httpClient.Transport = transport
+	c.client = &httpClient
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package gobark
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithConnectTimeout(t *testing.T) {
+	// The stub resolver never answers, so connecting stalls until the connect
+	// timeout, which also bounds the host lookup, expires
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+	client, err := NewClient("http://bark.example.internal", "test-key",
+		WithResolver(resolver), WithConnectTimeout(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The send outlives the connect timeout, so only the connect timeout can end it
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	err = client.Send(ctx, "test message")
+	elapsed := time.Since(start)
+
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("Send() error = %v, want a connect timeout", err)
+	}
+	if ctx.Err() != nil {
+		t.Fatalf("Send() ended by the context after %v, want the connect timeout", elapsed)
+	}
+	if elapsed < 100*time.Millisecond || elapsed > time.Second {
+		t.Errorf("Send() took %v, want it to fail at the connect timeout", elapsed)
+	}
+}
+
+func TestWithConnectTimeout_DoesNotModifyHTTPClient(t *testing.T) {
+	httpClient := &http.Client{Transport: &http.Transport{}}
+	client, err := NewClient("", "test-key", WithHTTPClient(httpClient), WithConnectTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if client.client == httpClient || client.client.Transport == httpClient.Transport {
+		t.Error("connect timeout modified the provided HTTP client")
+	}
+	if httpClient.Transport.(*http.Transport).DialContext != nil {
+		t.Error("connect timeout modified the provided transport")
+	}
+}
+
+func TestWithConnectTimeout_UnsupportedTransport(t *testing.T) {
+	httpClient := &http.Client{Transport: roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return nil, nil
+	})}
+	if _, err := NewClient("", "test-key", WithHTTPClient(httpClient), WithConnectTimeout(time.Second)); err == nil {
+		t.Error("NewClient() error = nil, want error for a non-*http.Transport")
+	}
+}
+
+func TestWithResolver(t *testing.T) {
+	var consulted bool
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			consulted = true
+			return nil, errors.New("stub resolver: no DNS server")
+		},
+	}
+
+	client, err := NewClient("http://bark.example.internal", "test-key", WithResolver(resolver))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Send(context.Background(), "test message"); err == nil {
+		t.Fatal("Send() error = nil, want lookup error from the stub resolver")
+	}
+	if !consulted {
+		t.Error("stub resolver was not consulted")
+	}
+}
+
+func TestWithTLSMinVersion(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		opts    []ClientOption
+		wantErr bool
+	}{
+		{name: "server meets minimum", opts: []ClientOption{WithTLSMinVersion(tls.VersionTLS12)}},
+		{name: "server below minimum", opts: []ClientOption{WithTLSMinVersion(tls.VersionTLS13)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]ClientOption{WithHTTPClient(server.Client())}, tt.opts...)
+			client, err := NewClient(server.URL, "test-key", opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = client.Send(context.Background(), "test message")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), "protocol version") {
+				t.Errorf("Send() error = %v, want a TLS version error", err)
+			}
+		})
+	}
+
+	if server.Client().Transport.(*http.Transport).TLSClientConfig.MinVersion != 0 {
+		t.Error("WithTLSMinVersion modified the TLS config of the HTTP client")
+	}
+}
+
+func TestWithTLSMinVersion_Invalid(t *testing.T) {
+	if _, err := NewClient("", "test-key", WithTLSMinVersion(0x0200)); err == nil {
+		t.Error("NewClient() with unsupported tls version error = nil, want error")
+	}
+}
+
+// roundTripperFunc adapts a function to an http.RoundTripper.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}